@@ -1,64 +1,8 @@
-package main
-
-import (
-	"fmt"
-	"io"
-	"log/slog"
-	"net/http"
-	"os"
-	"path"
-	"path/filepath"
-)
+package anilist
 
-// Image is a url to image
+// Image is the url of an image on AniList.
 type Image string
 
-// Download downloads the image and saves it to the disk.
-// Returns the path in which the image is downloaded.
-func (i Image) Download() (string, error) {
-	cacheDir, err := os.UserCacheDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get cache directory: %w", err)
-	}
-
-	cacheDir = filepath.Join(cacheDir, "anilist-grid", "images")
-	if err := os.MkdirAll(cacheDir, os.ModePerm); err != nil {
-		return "", fmt.Errorf("failed to create cache directory: %w", err)
-	}
-
-	filename := path.Base(string(i))
-	filePath := filepath.Join(cacheDir, filename)
-
-	if _, err := os.Stat(filePath); err == nil {
-		slog.Debug("Image already exists", "path", filePath)
-		return filePath, nil
-	}
-
-	resp, err := http.Get(string(i))
-	if err != nil {
-		return "", fmt.Errorf("failed to download image: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to download image: status code %d", resp.StatusCode)
-	}
-
-	file, err := os.Create(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create file: %w", err)
-	}
-	defer file.Close()
-
-	_, err = io.Copy(file, resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to save image: %w", err)
-	}
-
-	slog.Debug("Image downloaded successfully", "path", filePath)
-	return filePath, nil
-}
-
 // Viewer represents the root structure of a user profile.
 type Viewer struct {
 	Data struct {
@@ -80,6 +24,15 @@ type User struct {
 	Favourites Favourites `json:"favourites"`  // Favorite anime, manga, and characters.
 	ID         int64      `json:"id"`          // Unique identifier of the user.
 	Name       string     `json:"name"`        // Display name of the user.
+	SiteURL    string     `json:"siteUrl"`     // Profile page of the user.
+	Statistics Statistics `json:"statistics"`  // Totals AniList keeps for the user.
+}
+
+// Statistics holds the totals AniList computes for a user.
+type Statistics struct {
+	Anime struct {
+		MinutesWatched int64 `json:"minutesWatched"` // Time spent watching anime.
+	} `json:"anime"`
 }
 
 // Avatar holds different sizes of an avatar image.
@@ -100,6 +53,7 @@ type FavouriteNode struct {
 	Nodes `json:"nodes"` // Collection of favorite media entries.
 }
 
+// Has reports whether the media with id is a favourite.
 func (fn FavouriteNode) Has(id int64) bool {
 	for _, n := range fn.Nodes {
 		if n.ID == id {
@@ -124,8 +78,15 @@ type Characters struct {
 
 // CharactersNode represents a single favorite character entry.
 type CharactersNode struct {
-	ID    int64  `json:"id"`    // Unique identifier of the character.
-	Image Avatar `json:"image"` // Character's avatar image.
+	ID      int64  `json:"id"`      // Unique identifier of the character.
+	Name    Title  `json:"name"`    // Name of the character.
+	SiteURL string `json:"siteUrl"` // Page of the character.
+	Image   Avatar `json:"image"`   // Character's avatar image.
+}
+
+// Title holds the name of a media or character in the user's preferred language.
+type Title struct {
+	UserPreferred string `json:"userPreferred"` // Name in the user's preferred language.
 }
 
 // Status represents different statuses for a media list entry.
@@ -137,6 +98,7 @@ const (
 	Dropped   Status = "DROPPED"   // Dropped midway.
 	Paused    Status = "PAUSED"    // Temporarily on hold.
 	Planning  Status = "PLANNING"  // Planned for future.
+	Repeating Status = "REPEATING" // Watching/reading again.
 )
 
 // Type defines whether the media is anime or manga.
@@ -175,6 +137,9 @@ type Entry struct {
 // Media represents detailed information about a media entry.
 type Media struct {
 	ID           int64      `json:"id"`           //  ID of the media
+	IDMal        *int64     `json:"idMal"`        // MyAnimeList ID of the media.
+	Title        Title      `json:"title"`        // Title of the media.
+	SiteURL      string     `json:"siteUrl"`      // Page of the media.
 	AverageScore *int64     `json:"averageScore"` // Community average score.
 	Banner       *Image     `json:"bannerImage"`  // Banner image of the media.
 	Cover        CoverImage `json:"coverImage"`   // Cover image in different sizes.
@@ -192,6 +157,14 @@ type CoverImage struct {
 	Medium     Image   `json:"medium"`     // Medium-sized cover image.
 }
 
+// ColorHex returns the dominant color, or an empty string when AniList has none.
+func (c CoverImage) ColorHex() string {
+	if c.Color == nil {
+		return ""
+	}
+	return *c.Color
+}
+
 // AnimeList is a wrapper for anime-related media lists.
 type AnimeList struct {
 	ListData `json:"data"`
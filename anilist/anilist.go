@@ -1,4 +1,7 @@
-package main
+// Package anilist is a client for the AniList GraphQL API. It logs in with
+// OAuth2, fetches users and their anime and manga lists, and reads
+// MyAnimeList exports into the same types.
+package anilist
 
 import (
 	"bytes"
@@ -12,25 +15,35 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
+	"slices"
 
 	"golang.org/x/oauth2"
 )
 
 const (
-	AnilistRedirectURL = "https://anilist.co/api/v2/oauth/pin"
-	AnilistAuthURL     = "https://anilist.co/api/v2/oauth/authorize"
-	AnilistTokenURL    = "https://anilist.co/api/v2/oauth/token"
+	RedirectURL = "https://anilist.co/api/v2/oauth/pin"
+	AuthURL     = "https://anilist.co/api/v2/oauth/authorize"
+	TokenURL    = "https://anilist.co/api/v2/oauth/token"
 
 	Endpoint = "https://graphql.anilist.co"
 )
 
-const (
-	AnsiGreen = "\033[32m" // ANSI escape code for AnsiBlue
-	AnsiBlue  = "\033[34m" // ANSI escape code for blue
-	AnsiReset = "\033[0m"  // Reset color
+// Endpoints are the URLs the AniList client talks to. Tests point
+// them at a fake server.
+type Endpoints struct {
+	GraphQL     string
+	AuthURL     string
+	TokenURL    string
+	RedirectURL string
+}
 
-)
+// DefaultEndpoints are the endpoints of AniList.
+var DefaultEndpoints = Endpoints{
+	GraphQL:     Endpoint,
+	AuthURL:     AuthURL,
+	TokenURL:    TokenURL,
+	RedirectURL: RedirectURL,
+}
 
 //go:embed media-collection.graphql
 var MediaCollectionQuery string
@@ -41,11 +54,26 @@ var ViewerQuery string
 //go:embed user.graphql
 var UserQuery string
 
+//go:embed mal.graphql
+var MalMediaQuery string
+
+var (
+	// ErrNotFound is returned when AniList has no user with the given name.
+	ErrNotFound = errors.New("not found")
+	// ErrNoCredentials is returned by New without a client id and secret.
+	ErrNoCredentials = errors.New("missing client id or secret")
+	// ErrLoginRequired is returned by Login without a saved token. The user
+	// has to open LoginURL and the code they get is passed to Exchange.
+	ErrLoginRequired = errors.New("login required")
+)
+
+// GraphQL is the body of a request to the GraphQL API.
 type GraphQL struct {
 	Query     string         `json:"query"`
 	Variables map[string]any `json:"variables"`
 }
 
+// Json returns the request encoded as JSON.
 func (q GraphQL) Json() []byte {
 	b, err := json.Marshal(q)
 	if err != nil {
@@ -55,20 +83,23 @@ func (q GraphQL) Json() []byte {
 	return b
 }
 
-// Anilist holds the OAuth2 configuration and client
-type Anilist struct {
-	ctx    context.Context
-	oauth2 *oauth2.Config
-	tok    *oauth2.Token
-	http   *http.Client
+// Client holds the OAuth2 configuration and client
+type Client struct {
+	ctx      context.Context
+	oauth2   *oauth2.Config
+	tok      *oauth2.Token
+	http     *http.Client
+	endpoint string // GraphQL endpoint queries are sent to.
 }
 
+// Credentials are the client id and secret of an AniList API client.
 type Credentials struct {
 	ID     string `json:"client_id"`
 	Secret string `json:"client_secret"`
 }
 
-func saveCredentials(credentials Credentials) error {
+// SaveCredentials writes the credentials to the config directory.
+func SaveCredentials(credentials Credentials) error {
 	config, err := os.UserConfigDir()
 	if err != nil {
 		return err
@@ -90,7 +121,8 @@ func saveCredentials(credentials Credentials) error {
 	return json.NewEncoder(file).Encode(credentials)
 }
 
-func loadCredentials() (Credentials, error) {
+// LoadCredentials reads the credentials saved by SaveCredentials.
+func LoadCredentials() (Credentials, error) {
 	var credentials Credentials
 	config, err := os.UserConfigDir()
 	if err != nil {
@@ -112,54 +144,38 @@ func loadCredentials() (Credentials, error) {
 	return credentials, nil
 }
 
-func NewAnilist(ctx context.Context) *Anilist {
-	cred, err := loadCredentials()
-	if err != nil || cred.ID == "" || cred.Secret == "" {
-		var id string
-		var secret string
-
-		fmt.Printf("%sYou need create an Anilist app!%s\n", AnsiGreen, AnsiReset)
-		fmt.Printf(" - Goto %s%s%s\n", AnsiBlue, "https://anilist.co/settings/developer", AnsiReset)
-		fmt.Println(" - Create New Client")
-		fmt.Printf(" - Give it name and set Redirect URL to %s%s%s\n\n", AnsiBlue, AnilistRedirectURL, AnsiReset)
-
-		fmt.Print("Enter Client ID: ")
-		fmt.Scanln(&id)
-		fmt.Print("Enter Client Secret: ")
-		fmt.Scanln(&secret)
-
-		cred = Credentials{
-			ID:     strings.TrimSpace(id),
-			Secret: strings.TrimSpace(secret),
-		}
-
-		if cred.ID == "" || cred.Secret == "" {
-			panic("Invalid ID and Secret")
-		}
-
-		if err := saveCredentials(cred); err != nil {
-			slog.Error("Failed to save credentials: %v", "error", err)
-		}
+// New returns a client that logs in as the AniList API client of cred.
+func New(ctx context.Context, endpoints Endpoints, cred Credentials) (*Client, error) {
+	if cred.ID == "" || cred.Secret == "" {
+		return nil, ErrNoCredentials
 	}
 
 	oauth2 := &oauth2.Config{
 		ClientID:     cred.ID,
 		ClientSecret: cred.Secret,
-		Endpoint:     oauth2.Endpoint{AuthURL: AnilistAuthURL, TokenURL: AnilistTokenURL},
-		RedirectURL:  AnilistRedirectURL,
+		Endpoint:     oauth2.Endpoint{AuthURL: endpoints.AuthURL, TokenURL: endpoints.TokenURL},
+		RedirectURL:  endpoints.RedirectURL,
 		Scopes:       []string{},
 	}
 
-	return &Anilist{ctx: ctx, oauth2: oauth2}
+	return &Client{ctx: ctx, oauth2: oauth2, endpoint: endpoints.GraphQL}, nil
 }
 
-// AuthURL returns the authentication URL to redirect the user
-func (a *Anilist) LoginURL() string {
+// NewPublic creates a client without OAuth2 credentials. It can only
+// run queries that AniList allows without logging in, e.g. public profiles.
+// Requests are sent with the client stored in ctx under oauth2.HTTPClient.
+func NewPublic(ctx context.Context, endpoints Endpoints) *Client {
+	return &Client{ctx: ctx, http: clientFromContext(ctx), endpoint: endpoints.GraphQL}
+}
+
+// LoginURL returns the page where the user authorizes the client and gets
+// the code for Exchange.
+func (a *Client) LoginURL() string {
 	return a.oauth2.AuthCodeURL("")
 }
 
-// ExchangeCode exchanges an authorization code for an access token
-func (a *Anilist) Exchange(code string) error {
+// Exchange logs in with the authorization code from LoginURL.
+func (a *Client) Exchange(code string) error {
 	token, err := a.oauth2.Exchange(a.ctx, code)
 	if err != nil {
 		return err
@@ -172,7 +188,8 @@ func (a *Anilist) Exchange(code string) error {
 	return err
 }
 
-func (a *Anilist) SaveToken() error {
+// SaveToken writes the token of the client to the config directory.
+func (a *Client) SaveToken() error {
 	if a.tok == nil {
 		return errors.New("Token is nil")
 	}
@@ -198,7 +215,8 @@ func (a *Anilist) SaveToken() error {
 	return json.NewEncoder(file).Encode(a.tok)
 }
 
-func (a *Anilist) LoadToken() (*oauth2.Token, error) {
+// LoadToken reads the token saved by SaveToken.
+func (a *Client) LoadToken() (*oauth2.Token, error) {
 	config, err := os.UserConfigDir()
 	if err != nil {
 		return nil, err
@@ -224,42 +242,28 @@ func (a *Anilist) LoadToken() (*oauth2.Token, error) {
 	return &token, nil
 }
 
-func (a *Anilist) Login() error {
-	if tok, err := a.LoadToken(); err == nil {
-		src := a.oauth2.TokenSource(a.ctx, tok)
-		a.http = oauth2.NewClient(a.ctx, src)
-		return nil
-	} else {
-		slog.Warn("Anilist.Login: Failed to load access-token from disk", "reason", err)
-	}
-
-	fmt.Printf("%sOpen the following URL in your browser and authorize the application:%s\n", AnsiGreen, AnsiReset)
-	fmt.Printf(" - %s%s%s\n\n", AnsiBlue, a.LoginURL(), AnsiReset)
-
-	var code string
-	fmt.Print("Paste the code: ")
-	fmt.Scanln(&code)
-
-	code = strings.TrimSpace(code)
-	if code == "" {
-		panic("")
-	}
-
-	if err := a.Exchange(code); err != nil {
-		return err
+// Login logs in with the token saved by SaveToken. Without a usable token
+// it returns an error wrapping ErrLoginRequired.
+func (a *Client) Login() error {
+	tok, err := a.LoadToken()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrLoginRequired, err)
 	}
 
+	src := a.oauth2.TokenSource(a.ctx, tok)
+	a.http = oauth2.NewClient(a.ctx, src)
 	return nil
 }
 
-func (a *Anilist) GetCurrentUser() (Viewer, error) {
+// GetCurrentUser returns the logged in user.
+func (a *Client) GetCurrentUser() (Viewer, error) {
 	slog.Info("Anilist.GetCurrentUser: Fetching current user")
 	var user Viewer
 
 	query := GraphQL{Query: ViewerQuery, Variables: make(map[string]any)}
 	jsonBytes := query.Json()
 
-	req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, Endpoint, bytes.NewBuffer(jsonBytes))
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, a.endpoint, bytes.NewBuffer(jsonBytes))
 	if err != nil {
 		return user, err
 	}
@@ -283,14 +287,15 @@ func (a *Anilist) GetCurrentUser() (Viewer, error) {
 	return user, nil
 }
 
-func (a *Anilist) GetUser(username string) (Searched, error) {
-	slog.Info("Anilist.GetCurrentUser: Fetching current user")
+// GetUser returns the user named username.
+func (a *Client) GetUser(username string) (Searched, error) {
+	slog.Info("Anilist.GetUser: Fetching user", "username", username)
 	var user Searched
 
 	query := GraphQL{Query: UserQuery, Variables: map[string]any{"name": username}}
 	jsonBytes := query.Json()
 
-	req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, Endpoint, bytes.NewBuffer(jsonBytes))
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, a.endpoint, bytes.NewBuffer(jsonBytes))
 	if err != nil {
 		return user, err
 	}
@@ -303,6 +308,9 @@ func (a *Anilist) GetUser(username string) (Searched, error) {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return user, fmt.Errorf("user %q: %w", username, ErrNotFound)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return user, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
@@ -314,7 +322,56 @@ func (a *Anilist) GetUser(username string) (Searched, error) {
 	return user, nil
 }
 
-func (a *Anilist) GetList(id int64) (AnimeList, MangaList, error) {
+// GetMalMedia looks up AniList media by their MyAnimeList IDs. IDs without an
+// AniList counterpart are missing from the result.
+func (a *Client) GetMalMedia(ids []int64, t Type) ([]Media, error) {
+	slog.Info("Anilist.GetMalMedia: Fetching media by MyAnimeList id", "count", len(ids), "type", t)
+
+	var media []Media
+	for chunk := range slices.Chunk(ids, 50) {
+		var page struct {
+			Data struct {
+				Page struct {
+					Media []Media `json:"media"`
+				} `json:"Page"`
+			} `json:"data"`
+		}
+
+		query := GraphQL{Query: MalMediaQuery, Variables: map[string]any{"ids": chunk, "type": t}}
+		jsonBytes := query.Json()
+
+		req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, a.endpoint, bytes.NewBuffer(jsonBytes))
+		if err != nil {
+			return media, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := a.http.Do(req)
+		if err != nil {
+			return media, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return media, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(b))
+		}
+
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return media, err
+		}
+
+		media = append(media, page.Data.Page.Media...)
+	}
+
+	return media, nil
+}
+
+// GetList returns the anime and manga lists of the user with id.
+func (a *Client) GetList(id int64) (AnimeList, MangaList, error) {
 	type animeResult struct {
 		list AnimeList
 		err  error
@@ -336,7 +393,7 @@ func (a *Anilist) GetList(id int64) (AnimeList, MangaList, error) {
 		animeQuery := GraphQL{Query: MediaCollectionQuery, Variables: map[string]any{"userId": id, "type": "ANIME"}}
 		animeJsonBytes := animeQuery.Json()
 
-		animeReq, err := http.NewRequestWithContext(a.ctx, http.MethodPost, Endpoint, bytes.NewBuffer(animeJsonBytes))
+		animeReq, err := http.NewRequestWithContext(a.ctx, http.MethodPost, a.endpoint, bytes.NewBuffer(animeJsonBytes))
 		if err != nil {
 			animeCh <- animeResult{err: err}
 			return
@@ -375,7 +432,7 @@ func (a *Anilist) GetList(id int64) (AnimeList, MangaList, error) {
 		mangaQuery := GraphQL{Query: MediaCollectionQuery, Variables: map[string]any{"userId": id, "type": "MANGA"}}
 		mangaJsonBytes := mangaQuery.Json()
 
-		mangaReq, err := http.NewRequestWithContext(a.ctx, http.MethodPost, Endpoint, bytes.NewBuffer(mangaJsonBytes))
+		mangaReq, err := http.NewRequestWithContext(a.ctx, http.MethodPost, a.endpoint, bytes.NewBuffer(mangaJsonBytes))
 		if err != nil {
 			mangaCh <- mangaResult{err: err}
 			return
@@ -0,0 +1,25 @@
+package anilist
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNewWithoutCredentials(t *testing.T) {
+	if _, err := New(context.Background(), DefaultEndpoints, Credentials{ID: "1"}); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected ErrNoCredentials without a secret, got %v", err)
+	}
+}
+
+func TestLoginRequired(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	client, err := New(context.Background(), DefaultEndpoints, Credentials{ID: "1", Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Login(); !errors.Is(err, ErrLoginRequired) {
+		t.Errorf("Expected ErrLoginRequired without a saved token, got %v", err)
+	}
+}
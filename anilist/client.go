@@ -0,0 +1,160 @@
+package anilist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// DefaultUserAgent is sent with every request unless configured otherwise.
+const DefaultUserAgent = "hexanilist (+https://github.com/Nadim147c/hexanilist)"
+
+// HTTPConfig configures the HTTP client shared by the AniList API client
+// and the image downloader.
+type HTTPConfig struct {
+	Timeout   time.Duration     // Limit for a whole request, including retries.
+	UserAgent string            // User-Agent header.
+	Proxy     string            // Proxy url, the environment is used when empty.
+	Retries   int               // Retries for network errors, 429 and 5xx responses.
+	RetryWait time.Duration     // Wait before the first retry, doubled for each retry.
+	Rewrites  map[string]string // Url prefixes replaced before sending, e.g. for a mirror.
+}
+
+// DefaultHTTPConfig returns the configuration used when no flag is given.
+func DefaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		Timeout:   time.Minute,
+		UserAgent: DefaultUserAgent,
+		Retries:   3,
+		RetryWait: 500 * time.Millisecond,
+	}
+}
+
+// NewHTTPClient creates a client from the configuration.
+func NewHTTPClient(cfg HTTPConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: 15 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	transport.ResponseHeaderTimeout = cfg.Timeout
+
+	if cfg.Proxy != "" {
+		proxy, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	client := &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: &clientTransport{base: transport, cfg: cfg},
+	}
+
+	return client, nil
+}
+
+// clientTransport applies the rewrites, User-Agent and retry policy of a
+// HTTPConfig on top of a base transport.
+type clientTransport struct {
+	base http.RoundTripper
+	cfg  HTTPConfig
+}
+
+// RoundTrip sends req, retrying rate limited and failed requests.
+func (t *clientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	t.rewrite(req)
+
+	if t.cfg.UserAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", t.cfg.UserAgent)
+	}
+
+	wait := t.cfg.RetryWait
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+
+		if attempt >= t.cfg.Retries || !retryable(resp, err) {
+			return resp, err
+		}
+
+		// The body has been consumed, it can only be replayed when it can be
+		// recreated
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, berr := req.GetBody()
+			if berr != nil {
+				return resp, err
+			}
+			req.Body = body
+		}
+
+		delay := wait
+		if resp != nil {
+			if after, perr := strconv.Atoi(resp.Header.Get("Retry-After")); perr == nil {
+				delay = time.Duration(after) * time.Second
+			}
+			resp.Body.Close()
+		}
+
+		slog.Debug("Retrying request", "url", req.URL.String(), "attempt", attempt+1, "wait", delay, "error", err)
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+		wait *= 2
+	}
+}
+
+// rewrite replaces the longest matching url prefix of the request, so a
+// rewrite of a path wins over one of its host.
+func (t *clientTransport) rewrite(req *http.Request) {
+	original := req.URL.String()
+
+	var from string
+	for prefix := range t.cfg.Rewrites {
+		if strings.HasPrefix(original, prefix) && len(prefix) > len(from) {
+			from = prefix
+		}
+	}
+	if from == "" {
+		return
+	}
+
+	u, err := url.Parse(t.cfg.Rewrites[from] + strings.TrimPrefix(original, from))
+	if err != nil {
+		slog.Warn("Invalid rewritten url", "from", original, "error", err)
+		return
+	}
+
+	req.URL = u
+	req.Host = ""
+}
+
+// retryable reports whether a request failed in a way worth retrying.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// clientFromContext returns the client stored in ctx under oauth2.HTTPClient,
+// falling back to http.DefaultClient.
+func clientFromContext(ctx context.Context) *http.Client {
+	if client, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok && client != nil {
+		return client
+	}
+	return http.DefaultClient
+}
@@ -0,0 +1,139 @@
+package anilist
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestHTTPClientUserAgent(t *testing.T) {
+	var agent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agent = r.Header.Get("User-Agent")
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClient(DefaultHTTPConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if agent != DefaultUserAgent {
+		t.Errorf("Expected User-Agent %q, got %q", DefaultUserAgent, agent)
+	}
+}
+
+func TestHTTPClientRetries(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	cfg := DefaultHTTPConfig()
+	cfg.RetryWait = time.Millisecond
+	client, err := NewHTTPClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("query"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "query" {
+		t.Errorf("Expected replayed body after retries, got %d %q", resp.StatusCode, body)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("Expected 3 requests, got %d", n)
+	}
+
+	cfg.Retries = 0
+	client, _ = NewHTTPClient(cfg)
+	requests.Store(0)
+
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable || requests.Load() != 1 {
+		t.Errorf("Expected no retry, got status %d after %d requests", resp.StatusCode, requests.Load())
+	}
+}
+
+func TestHTTPClientRewrites(t *testing.T) {
+	var path string
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+	}))
+	defer mirror.Close()
+
+	cfg := DefaultHTTPConfig()
+	cfg.Rewrites = map[string]string{
+		"https://s4.anilist.co":                      mirror.URL + "/host",
+		"https://s4.anilist.co/file":                 mirror.URL + "/mirror",
+		"https://s4.anilist.co/file/anilistcdn/user": mirror.URL + "/user",
+	}
+	client, err := NewHTTPClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Get("https://s4.anilist.co/file/anilistcdn/media/anime/cover/medium/1.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if path != "/mirror/anilistcdn/media/anime/cover/medium/1.jpg" {
+		t.Errorf("Expected request to be rewritten to the mirror, got %s", path)
+	}
+
+	// The longest matching prefix wins whatever the order of the map
+	for range 20 {
+		resp, err := client.Get("https://s4.anilist.co/file/anilistcdn/user/avatar/1.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if path != "/user/avatar/1.png" {
+			t.Fatalf("Expected the longest prefix to be rewritten, got %s", path)
+		}
+	}
+}
+
+func TestClientFromContext(t *testing.T) {
+	if clientFromContext(context.Background()) != http.DefaultClient {
+		t.Errorf("Expected default client without a configured client")
+	}
+
+	client := &http.Client{}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client)
+	if clientFromContext(ctx) != client {
+		t.Errorf("Expected configured client")
+	}
+}
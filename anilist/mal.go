@@ -0,0 +1,300 @@
+package anilist
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MalExport is the root of a MyAnimeList list export. Anime and manga exports
+// share the format, only one of Anime or Manga is filled.
+type MalExport struct {
+	XMLName xml.Name   `xml:"myanimelist"`
+	Info    MalInfo    `xml:"myinfo"` // Export metadata.
+	Anime   []MalEntry `xml:"anime"`  // Anime entries of an anime export.
+	Manga   []MalEntry `xml:"manga"`  // Manga entries of a manga export.
+}
+
+// MalInfo holds the metadata of a MyAnimeList export.
+type MalInfo struct {
+	UserID   int64  `xml:"user_id"`   // MyAnimeList user ID.
+	UserName string `xml:"user_name"` // MyAnimeList user name.
+}
+
+// MalEntry is a single anime or manga entry of a MyAnimeList export.
+type MalEntry struct {
+	AnimeID    int64   `xml:"series_animedb_id"` // MyAnimeList ID of an anime entry.
+	MangaID    int64   `xml:"manga_mangadb_id"`  // MyAnimeList ID of a manga entry.
+	AnimeTitle string  `xml:"series_title"`      // Title of an anime entry.
+	MangaTitle string  `xml:"manga_title"`       // Title of a manga entry.
+	Score      float64 `xml:"my_score"`          // User-assigned score, 0 when unscored.
+	Status     string  `xml:"my_status"`         // User-assigned status, e.g. "Plan to Watch".
+}
+
+// ID returns the MyAnimeList ID of the entry.
+func (e MalEntry) ID() int64 {
+	if e.AnimeID != 0 {
+		return e.AnimeID
+	}
+	return e.MangaID
+}
+
+// Title returns the title of the entry.
+func (e MalEntry) Title() string {
+	if e.AnimeTitle != "" {
+		return e.AnimeTitle
+	}
+	return e.MangaTitle
+}
+
+// Entry converts the MyAnimeList entry to an AniList entry. The media only
+// has its MyAnimeList ID and title set.
+func (e MalEntry) Entry(t Type) Entry {
+	id := e.ID()
+	entry := Entry{
+		Media:  Media{IDMal: &id, Type: t, Title: Title{UserPreferred: e.Title()}},
+		Status: malStatus(e.Status),
+	}
+
+	if e.Score > 0 {
+		score := e.Score
+		entry.Score = &score
+	}
+
+	return entry
+}
+
+// malStatus converts MyAnimeList status to AniList status.
+func malStatus(status string) Status {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "watching", "reading", "1":
+		return Current
+	case "completed", "2":
+		return Completed
+	case "on-hold", "3":
+		return Paused
+	case "dropped", "4":
+		return Dropped
+	default:
+		return Planning
+	}
+}
+
+// LoadMalExport reads a MyAnimeList export. Gzip compressed exports, which is
+// what MyAnimeList hands out, are decompressed transparently.
+func LoadMalExport(path string) (MalExport, error) {
+	var export MalExport
+
+	file, err := os.Open(path)
+	if err != nil {
+		return export, err
+	}
+	defer file.Close()
+
+	buf := bufio.NewReader(file)
+
+	var r io.Reader = buf
+	if magic, err := buf.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buf)
+		if err != nil {
+			return export, fmt.Errorf("failed to decompress %s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	if err := xml.NewDecoder(r).Decode(&export); err != nil {
+		return export, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return export, nil
+}
+
+// MalMapping maps MyAnimeList IDs to AniList media. It is stored as JSON so
+// covers can be resolved without reaching AniList.
+type MalMapping struct {
+	Anime map[int64]Media `json:"anime"` // Anime by MyAnimeList ID.
+	Manga map[int64]Media `json:"manga"` // Manga by MyAnimeList ID.
+}
+
+// LoadMalMapping reads a mapping file. A missing file is an empty mapping.
+func LoadMalMapping(path string) (MalMapping, error) {
+	mapping := MalMapping{Anime: map[int64]Media{}, Manga: map[int64]Media{}}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return mapping, nil
+	}
+	if err != nil {
+		return mapping, err
+	}
+	defer file.Close()
+
+	if err := json.NewDecoder(file).Decode(&mapping); err != nil {
+		return mapping, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if mapping.Anime == nil {
+		mapping.Anime = map[int64]Media{}
+	}
+	if mapping.Manga == nil {
+		mapping.Manga = map[int64]Media{}
+	}
+
+	return mapping, nil
+}
+
+// Save writes the mapping to path.
+func (m MalMapping) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
+
+// media returns the mapping table for the media type.
+func (m MalMapping) media(t Type) map[int64]Media {
+	if t == Manga {
+		return m.Manga
+	}
+	return m.Anime
+}
+
+// Resolve looks up every MyAnimeList ID missing from the mapping on AniList
+// and adds the result to the mapping. It reports whether the mapping changed.
+func (m MalMapping) Resolve(client *Client, ids []int64, t Type) (bool, error) {
+	table := m.media(t)
+
+	var missing []int64
+	for _, id := range ids {
+		if _, ok := table[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) == 0 {
+		return false, nil
+	}
+
+	media, err := client.GetMalMedia(missing, t)
+	for _, md := range media {
+		if md.IDMal != nil {
+			table[*md.IDMal] = md
+		}
+	}
+
+	return len(media) != 0, err
+}
+
+// Lists converts the export to AniList lists grouped by status. Covers are
+// taken from the mapping, entries without a mapping keep an empty cover.
+func (e MalExport) Lists(mapping MalMapping) (AnimeList, MangaList) {
+	var anime AnimeList
+	var manga MangaList
+
+	anime.Lists = malLists(e.Anime, Anime, mapping)
+	manga.Lists = malLists(e.Manga, Manga, mapping)
+
+	return anime, manga
+}
+
+func malLists(entries []MalEntry, t Type, mapping MalMapping) []List {
+	table := mapping.media(t)
+
+	var lists []List
+	index := make(map[Status]int)
+
+	for _, e := range entries {
+		entry := e.Entry(t)
+		if md, ok := table[e.ID()]; ok {
+			md.IDMal = entry.IDMal
+			md.Type = t
+			if md.Title.UserPreferred == "" {
+				md.Title = entry.Title
+			}
+			entry.Media = md
+		}
+
+		i, ok := index[entry.Status]
+		if !ok {
+			i = len(lists)
+			index[entry.Status] = i
+			lists = append(lists, List{Name: string(entry.Status), Status: entry.Status})
+		}
+		lists[i].Entries = append(lists[i].Entries, entry)
+	}
+
+	return lists
+}
+
+// LoadMalLists reads MyAnimeList exports and converts them to AniList lists.
+// Covers are resolved through the mapping file first and AniList second. When
+// AniList can't be reached the entries missing from the mapping have no cover.
+func LoadMalLists(client *Client, exports []string, mappingPath string) (MalInfo, AnimeList, MangaList, error) {
+	var info MalInfo
+	var merged MalExport
+
+	for _, path := range exports {
+		export, err := LoadMalExport(path)
+		if err != nil {
+			return info, AnimeList{}, MangaList{}, err
+		}
+
+		if info.UserName == "" {
+			info = export.Info
+		}
+
+		merged.Anime = append(merged.Anime, export.Anime...)
+		merged.Manga = append(merged.Manga, export.Manga...)
+	}
+
+	mapping := MalMapping{Anime: map[int64]Media{}, Manga: map[int64]Media{}}
+	if mappingPath != "" {
+		m, err := LoadMalMapping(mappingPath)
+		if err != nil {
+			return info, AnimeList{}, MangaList{}, err
+		}
+		mapping = m
+	}
+
+	changed := false
+	for t, entries := range map[Type][]MalEntry{Anime: merged.Anime, Manga: merged.Manga} {
+		ids := make([]int64, len(entries))
+		for i, e := range entries {
+			ids[i] = e.ID()
+		}
+
+		ok, err := mapping.Resolve(client, ids, t)
+		if err != nil {
+			slog.Warn("Failed to resolve MyAnimeList covers", "type", t, "error", err)
+		}
+		changed = changed || ok
+	}
+
+	if changed && mappingPath != "" {
+		if err := mapping.Save(mappingPath); err != nil {
+			slog.Error("Failed to save MyAnimeList mapping", "path", mappingPath, "error", err)
+		}
+	}
+
+	anime, manga := merged.Lists(mapping)
+	return info, anime, manga, nil
+}
@@ -0,0 +1,128 @@
+package anilist
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const malAnimeExport = `<?xml version="1.0" encoding="UTF-8" ?>
+<myanimelist>
+	<myinfo>
+		<user_id>42</user_id>
+		<user_name>Nadim</user_name>
+		<user_export_type>1</user_export_type>
+	</myinfo>
+	<anime>
+		<series_animedb_id>1</series_animedb_id>
+		<series_title><![CDATA[Cowboy Bebop]]></series_title>
+		<my_score>9</my_score>
+		<my_status>Completed</my_status>
+	</anime>
+	<anime>
+		<series_animedb_id>5</series_animedb_id>
+		<series_title><![CDATA[Cowboy Bebop: Tengoku no Tobira]]></series_title>
+		<my_score>0</my_score>
+		<my_status>Plan to Watch</my_status>
+	</anime>
+</myanimelist>`
+
+func TestLoadMalExport(t *testing.T) {
+	dir := t.TempDir()
+
+	plain := filepath.Join(dir, "animelist.xml")
+	if err := os.WriteFile(plain, []byte(malAnimeExport), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	compressed := filepath.Join(dir, "animelist.xml.gz")
+	file, err := os.Create(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	gz.Write([]byte(malAnimeExport))
+	gz.Close()
+	file.Close()
+
+	for _, path := range []string{plain, compressed} {
+		export, err := LoadMalExport(path)
+		if err != nil {
+			t.Fatalf("LoadMalExport(%s) failed: %v", path, err)
+		}
+
+		if export.Info.UserName != "Nadim" {
+			t.Errorf("Expected user name Nadim, got %s", export.Info.UserName)
+		}
+
+		if len(export.Anime) != 2 {
+			t.Fatalf("Expected 2 anime entries, got %d", len(export.Anime))
+		}
+
+		if export.Anime[0].ID() != 1 || export.Anime[0].Score != 9 {
+			t.Errorf("Unexpected first entry %+v", export.Anime[0])
+		}
+	}
+}
+
+func TestMalStatus(t *testing.T) {
+	tests := map[string]Status{
+		"Watching":      Current,
+		"Reading":       Current,
+		"Completed":     Completed,
+		"On-Hold":       Paused,
+		"Dropped":       Dropped,
+		"Plan to Watch": Planning,
+		"Plan to Read":  Planning,
+	}
+
+	for status, expected := range tests {
+		if got := malStatus(status); got != expected {
+			t.Errorf("malStatus(%q) = %s; want %s", status, got, expected)
+		}
+	}
+}
+
+func TestMalExportLists(t *testing.T) {
+	export := MalExport{
+		Anime: []MalEntry{
+			{AnimeID: 1, Score: 9, Status: "Completed"},
+			{AnimeID: 5, Status: "Plan to Watch"},
+		},
+		Manga: []MalEntry{
+			{MangaID: 2, Score: 7, Status: "Reading"},
+		},
+	}
+
+	mapping := MalMapping{
+		Anime: map[int64]Media{1: {ID: 1, Cover: CoverImage{Medium: "https://example.com/1.jpg"}}},
+		Manga: map[int64]Media{},
+	}
+
+	anime, manga := export.Lists(mapping)
+
+	if len(anime.Lists) != 2 {
+		t.Fatalf("Expected 2 anime lists, got %d", len(anime.Lists))
+	}
+
+	completed := anime.Lists[0].Entries[0]
+	if completed.Status != Completed || completed.ID != 1 || completed.Cover.Medium != "https://example.com/1.jpg" {
+		t.Errorf("Unexpected completed entry %+v", completed)
+	}
+	if completed.Score == nil || *completed.Score != 9 {
+		t.Errorf("Expected score 9, got %v", completed.Score)
+	}
+
+	planned := anime.Lists[1].Entries[0]
+	if planned.Score != nil {
+		t.Errorf("Expected unscored entry to have nil score, got %v", *planned.Score)
+	}
+	if planned.IDMal == nil || *planned.IDMal != 5 {
+		t.Errorf("Expected MyAnimeList ID 5, got %v", planned.IDMal)
+	}
+
+	if len(manga.Lists) != 1 || manga.Lists[0].Entries[0].Type != Manga {
+		t.Errorf("Unexpected manga lists %+v", manga.Lists)
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Nadim147c/hexanilist/anilist"
+	"github.com/Nadim147c/hexanilist/hexgrid"
+	"github.com/Nadim147c/hexanilist/internal/fakeanilist"
+	"github.com/Nadim147c/hexanilist/render"
+	"github.com/fogleman/gg"
+)
+
+// setFlag sets the flag variable p to v until the test ends.
+func setFlag[T any](t *testing.T, p *T, v T) {
+	t.Helper()
+	old := *p
+	*p = v
+	t.Cleanup(func() { *p = old })
+}
+
+// useFakeAnilist starts a fake AniList server and points the flags at it.
+// The user is logged in with an expired token that has to be refreshed, and
+// the config, image cache and output live in temporary directories.
+func useFakeAnilist(t *testing.T) *fakeanilist.Server {
+	t.Helper()
+
+	srv := fakeanilist.New()
+	t.Cleanup(srv.Close)
+
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	dir := filepath.Join(config, "anilist-gird")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"client.json": `{"client_id":"1","client_secret":"secret"}`,
+		"access.json": fmt.Sprintf(`{"access_token":"expired","token_type":"Bearer","refresh_token":%q,"expiry":"2000-01-01T00:00:00Z"}`, fakeanilist.RefreshToken),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := anilist.DefaultHTTPConfig()
+	client.Retries = 0
+	client.Rewrites = map[string]string{fakeanilist.CDN: srv.URL}
+	setFlag(t, &Client, client)
+	setFlag(t, &Endpoints, anilist.Endpoints{
+		GraphQL:     srv.GraphQL(),
+		AuthURL:     srv.AuthURL(),
+		TokenURL:    srv.TokenURL(),
+		RedirectURL: srv.URL + "/oauth/pin",
+	})
+	setFlag(t, &CacheDir, t.TempDir())
+	setFlag(t, &Output, filepath.Join(t.TempDir(), "grid"))
+	setFlag(t, &Size, 400)
+	setFlag(t, &CellSize, 30)
+	setFlag(t, &ShowProgress, false)
+	return srv
+}
+
+func TestEndToEnd(t *testing.T) {
+	srv := useFakeAnilist(t)
+	setFlag(t, &Badges, true)
+	setFlag(t, &ShowHeader, true)
+	output := Output + ".png"
+
+	if err := run(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := gg.LoadPNG(output)
+	if err != nil {
+		t.Fatalf("Expected the grid at %s: %v", output, err)
+	}
+	if b := img.Bounds(); b.Dx() != 400 || b.Dy() != 400+render.HeaderHeight(400) {
+		t.Errorf("Expected a 400px grid below the header, got %v", b)
+	}
+
+	if n := srv.Requests("token"); n != 1 {
+		t.Errorf("Expected the expired token to be refreshed once, got %d requests", n)
+	}
+	if n := srv.Requests("Viewer"); n != 1 {
+		t.Errorf("Expected one Viewer query, got %d", n)
+	}
+	if n := srv.Requests("MediaListCollection"); n != 2 {
+		t.Errorf("Expected the anime and manga lists to be queried, got %d queries", n)
+	}
+	images := srv.Requests("image")
+	if images == 0 {
+		t.Fatalf("Expected covers to be downloaded from the fake CDN")
+	}
+
+	// Covers are served from the cache the second time
+	if err := run(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Requests("image"); n != images {
+		t.Errorf("Expected cached covers to be reused, got %d more downloads", n-images)
+	}
+}
+
+func TestEndToEndUser(t *testing.T) {
+	srv := useFakeAnilist(t)
+	setFlag(t, &Username, srv.UserName())
+
+	if err := run(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(Output + ".png"); err != nil {
+		t.Errorf("Expected the grid of %s: %v", srv.UserName(), err)
+	}
+	if n := srv.Requests("User"); n != 1 {
+		t.Errorf("Expected one User query, got %d", n)
+	}
+
+	setFlag(t, &Username, "nobody")
+	err := run(context.Background(), nil)
+	if err == nil || exitCode(err) != 1 {
+		t.Errorf("Expected an error for an unknown user, got %v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook needs a POSIX shell")
+	}
+	srv := useFakeAnilist(t)
+	hooks := filepath.Join(t.TempDir(), "hooks.txt")
+	setFlag(t, &Watch, 10*time.Millisecond)
+	setFlag(t, &OnRender, `echo "$HEXANILIST_OUTPUT" >> `+hooks)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, nil) }()
+
+	// The client logged in once is used by every poll, polls keep working
+	// without the saved token
+	deadline := time.Now().Add(10 * time.Second)
+	for srv.Requests("Viewer") < 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	config, _ := os.UserConfigDir()
+	if err := os.Remove(filepath.Join(config, "anilist-gird", "access.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for a few polls of the unchanged lists
+	for srv.Requests("Viewer") < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected watching to stop with the context, got %v", err)
+	}
+	if n := srv.Requests("Viewer"); n < 3 {
+		t.Fatalf("Expected the lists to be polled, got %d Viewer queries", n)
+	}
+	if n := srv.Requests("token"); n != 1 {
+		t.Errorf("Expected the token to be refreshed once, got %d requests", n)
+	}
+
+	b, err := os.ReadFile(hooks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), Output+".png\n"; got != want {
+		t.Errorf("Expected the hook to run once for an unchanged list, got %q", got)
+	}
+
+	// Outputs are renamed into place, no temporary file is left behind
+	files, _ := filepath.Glob(filepath.Join(filepath.Dir(Output), "*"))
+	if len(files) != 1 {
+		t.Errorf("Expected only the output, got %v", files)
+	}
+}
+
+func TestRunUsageError(t *testing.T) {
+	srv := useFakeAnilist(t)
+
+	tests := map[string]func(t *testing.T){
+		"layout":  func(t *testing.T) { setFlag(t, &GridLayout, "spiral") },
+		"animate": func(t *testing.T) { setFlag(t, &Animate, "webm") },
+	}
+	for name, set := range tests {
+		t.Run(name, func(t *testing.T) {
+			set(t)
+			if err := run(context.Background(), nil); exitCode(err) != 2 {
+				t.Errorf("Expected a usage error for an invalid %s, got %v", name, err)
+			}
+		})
+	}
+
+	if n := srv.Requests("Viewer"); n != 0 {
+		t.Errorf("Expected flags to be checked before AniList is queried, got %d queries", n)
+	}
+}
+
+func TestSaveAnimationExtension(t *testing.T) {
+	dir := t.TempDir()
+	final := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	hexs := hexgrid.GenerateHexagonRing(1, 50, 50, 20)
+
+	tests := map[string]string{
+		"hexagon.png": "hexagon.gif",
+		"grid":        "grid.gif",
+		"grid.gif":    "grid.gif",
+	}
+	for output, want := range tests {
+		anim := render.Animation{Format: render.AnimateGIF, FramesPerRing: 1}
+		got, err := saveAnimation(filepath.Join(dir, output), anim, final, hexs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != filepath.Join(dir, want) {
+			t.Errorf("Expected %s to be saved as %s, got %s", output, want, got)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{usagef("invalid layout"), 2},
+		{fmt.Errorf("render interrupted: %w", context.Canceled), 130},
+		{errors.New("unexpected status code: 404"), 1},
+	}
+
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("Expected exit code %d for %v, got %d", tt.want, tt.err, got)
+		}
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	// Redirected stderr gets no progress line
+	for _, f := range []*os.File{file, w} {
+		if isTerminal(f) {
+			t.Errorf("Expected %s not to be a terminal", f.Name())
+		}
+	}
+}
@@ -6,10 +6,9 @@ require (
 	github.com/disintegration/imaging v1.6.2
 	github.com/fogleman/gg v1.3.0
 	github.com/spf13/pflag v1.0.6
+	golang.org/x/image v0.25.0
 	golang.org/x/oauth2 v0.28.0
+	golang.org/x/sync v0.12.0
 )
 
-require (
-	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
-	golang.org/x/image v0.25.0 // indirect
-)
+require github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
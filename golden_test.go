@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Nadim147c/hexanilist/anilist"
+	"github.com/Nadim147c/hexanilist/hexgrid"
+	"github.com/Nadim147c/hexanilist/render"
+	"github.com/Nadim147c/hexanilist/source"
+	"github.com/fogleman/gg"
+)
+
+// updateGolden rewrites the golden images instead of comparing against them.
+// Run UPDATE_GOLDEN=1 go test -run TestGolden after intended visual changes.
+var updateGolden = os.Getenv("UPDATE_GOLDEN") != ""
+
+// goldenTolerance is how far apart two pixels may be before they count as
+// different, and goldenMaxDiff the share of pixels that may differ.
+const (
+	goldenTolerance = 24.0
+	goldenMaxDiff   = 0.002
+)
+
+// loadFixture loads the AniList profile of testdata/golden, whose covers are
+// the local images served by internal/fakeanilist.
+func loadFixture(t *testing.T) source.Anilist {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/golden/profile.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var profile struct {
+		User  anilist.User      `json:"user"`
+		Anime anilist.AnimeList `json:"anime"`
+		Manga anilist.MangaList `json:"manga"`
+	}
+	if err := json.Unmarshal(data, &profile); err != nil {
+		t.Fatal(err)
+	}
+	return source.Anilist{User: profile.User, Anime: profile.Anime, Manga: profile.Manga}
+}
+
+// goldenFooter is the date in the footer of golden images, fixed so they
+// don't change from day to day.
+var goldenFooter = time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+// goldenCase is a grid rendered from the fixture profile.
+type goldenCase struct {
+	name   string
+	grid   render.Grid // Size defaults to 400x400.
+	header bool        // Draw the header of the fixture user.
+}
+
+// render renders the fixture profile the way main does.
+func (c goldenCase) render(t *testing.T) *image.RGBA {
+	t.Helper()
+
+	src := loadFixture(t)
+	nodes, err := src.Nodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := c.grid
+	if g.Width == 0 {
+		g.Width, g.Height = 400, 400
+	}
+	if g.Renderer.Stroke.Align == "" {
+		g.Renderer.Stroke.Align = render.StrokeCenter
+	}
+	g.Renderer.Downloads = 4
+	g.Renderer.Placeholder = render.PlaceholderNone
+	if c.header {
+		header := render.NewHeader(src.User, src.Anime, src.Manga)
+		g.Header = &header
+	}
+
+	img, err := g.Draw(context.Background(), nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img.Image
+}
+
+var goldenCases = []goldenCase{
+	{
+		name: "rings",
+		grid: render.Grid{
+			Cell:     hexgrid.Cell{Radius: 36, Orientation: hexgrid.Flat},
+			Layout:   hexgrid.LayoutRings,
+			Order:    hexgrid.OrderScore,
+			Renderer: render.Renderer{Stroke: render.Stroke{Width: 3, DashPlanning: true}},
+		},
+	},
+	{
+		name: "sectors-pointy",
+		grid: render.Grid{
+			Cell:   hexgrid.Cell{Radius: 36, Orientation: hexgrid.Pointy, Gap: 4, Corner: 6},
+			Layout: hexgrid.LayoutSectors,
+			Order:  hexgrid.OrderScore,
+			Renderer: render.Renderer{
+				Overlay: render.Overlay{Badges: true},
+				Stroke:  render.Stroke{Width: 3, Align: render.StrokeInner, DashPlanning: true, Shadow: true},
+			},
+		},
+	},
+	{
+		name: "multi-scale",
+		grid: render.Grid{
+			Cell:       hexgrid.Cell{Radius: 22, Orientation: hexgrid.Flat, Gap: 2},
+			Layout:     hexgrid.LayoutRings,
+			Order:      hexgrid.OrderScore,
+			MultiScale: true,
+			Renderer: render.Renderer{
+				Overlay: render.Overlay{Labels: true, Badges: true},
+				Stroke:  render.Stroke{Width: 2, DashPlanning: true},
+			},
+		},
+	},
+	{
+		name: "heart-color",
+		grid: render.Grid{
+			Cell:     hexgrid.Cell{Radius: 24, Orientation: hexgrid.Flat, Gap: 2},
+			Layout:   hexgrid.LayoutRings,
+			Order:    hexgrid.OrderColor,
+			Shape:    "heart",
+			Renderer: render.Renderer{Stroke: render.Stroke{Width: 2}},
+		},
+	},
+	{
+		name: "rect-score-tl",
+		grid: render.Grid{
+			Cell:     hexgrid.Cell{Radius: 30, Orientation: hexgrid.Flat, Gap: 2},
+			Width:    400,
+			Height:   200,
+			Layout:   hexgrid.LayoutRect,
+			Order:    hexgrid.OrderScoreTopLeft,
+			Renderer: render.Renderer{Stroke: render.Stroke{Width: 2, Align: render.StrokeOuter}},
+		},
+	},
+	{
+		name:   "header-footer",
+		header: true,
+		grid: render.Grid{
+			Cell:     hexgrid.Cell{Radius: 30, Orientation: hexgrid.Flat},
+			Layout:   hexgrid.LayoutRings,
+			Order:    hexgrid.OrderRandom,
+			Seed:     7,
+			Footer:   goldenFooter,
+			Renderer: render.Renderer{Stroke: render.Stroke{Width: 2, DashPlanning: true}},
+		},
+	},
+}
+
+func TestGolden(t *testing.T) {
+	for _, c := range goldenCases {
+		t.Run(c.name, func(t *testing.T) {
+			got := c.render(t)
+			path := filepath.Join("testdata", "golden", c.name+".png")
+
+			if updateGolden {
+				dc := gg.NewContextForRGBA(got)
+				if err := dc.SavePNG(path); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := gg.LoadPNG(path)
+			if err != nil {
+				t.Fatalf("Failed to load golden image, run with UPDATE_GOLDEN=1 to create it: %v", err)
+			}
+
+			if diff := imageDiff(got, want); diff > goldenMaxDiff {
+				out := filepath.Join(os.TempDir(), "hexanilist-"+c.name+".png")
+				gg.NewContextForRGBA(got).SavePNG(out)
+				t.Errorf("Expected at most %.1f%% of pixels to differ from %s, got %.2f%%, wrote %s",
+					goldenMaxDiff*100, path, diff*100, out)
+			}
+		})
+	}
+}
+
+// imageDiff returns the share of pixels of a and b that look different,
+// weighting the channels by how much they matter to the eye.
+func imageDiff(a *image.RGBA, b image.Image) float64 {
+	if a.Bounds() != b.Bounds() {
+		return 1
+	}
+
+	rgba := image.NewRGBA(b.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), b, b.Bounds().Min, draw.Src)
+
+	differ := 0
+	for i := 0; i < len(a.Pix); i += 4 {
+		dr := float64(a.Pix[i]) - float64(rgba.Pix[i])
+		dg := float64(a.Pix[i+1]) - float64(rgba.Pix[i+1])
+		db := float64(a.Pix[i+2]) - float64(rgba.Pix[i+2])
+		da := float64(a.Pix[i+3]) - float64(rgba.Pix[i+3])
+		if math.Sqrt(0.299*dr*dr+0.587*dg*dg+0.114*db*db+da*da) > goldenTolerance {
+			differ++
+		}
+	}
+	return float64(differ) / float64(len(a.Pix)/4)
+}
+
+func TestImageDiff(t *testing.T) {
+	a := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	b := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	draw.Draw(a, a.Bounds(), image.NewUniform(color.RGBA{0x80, 0x80, 0x80, 0xff}), image.Point{}, draw.Src)
+	draw.Draw(b, b.Bounds(), image.NewUniform(color.RGBA{0x84, 0x82, 0x80, 0xff}), image.Point{}, draw.Src)
+
+	if diff := imageDiff(a, b); diff != 0 {
+		t.Errorf("Expected slight colour shifts to be tolerated, got %f", diff)
+	}
+
+	b.Set(5, 5, color.Black)
+	if diff := imageDiff(a, b); diff != 0.0001 {
+		t.Errorf("Expected one pixel in 10000 to differ, got %f", diff)
+	}
+
+	if diff := imageDiff(a, image.NewRGBA(image.Rect(0, 0, 10, 10))); diff != 1 {
+		t.Errorf("Expected images of other sizes to differ entirely, got %f", diff)
+	}
+}
+
+func TestDeterministicNodes(t *testing.T) {
+	src := loadFixture(t)
+
+	first, _ := src.Nodes()
+	for range 20 {
+		if nodes, _ := src.Nodes(); !slices.Equal(nodes, first) {
+			t.Fatalf("Expected the same order on every run")
+		}
+	}
+
+	// Equal scores are ordered by ID
+	var tied []int64
+	for _, node := range first {
+		if node.Type == hexgrid.AnimeNode && node.UserScore == 8 {
+			tied = append(tied, node.ID)
+		}
+	}
+	if !slices.Equal(tied, []int64{101, 102, 103, 104}) {
+		t.Errorf("Expected tied anime ordered by ID, got %v", tied)
+	}
+}
+
+func TestDeterministicRender(t *testing.T) {
+	c := goldenCases[1]
+	first := c.render(t)
+	for range 3 {
+		if got := c.render(t); !slices.Equal(got.Pix, first.Pix) {
+			t.Fatalf("Expected the same image on every render")
+		}
+	}
+}
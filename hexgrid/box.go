@@ -1,37 +1,44 @@
-package main
+package hexgrid
 
 import (
 	"image"
 	"math"
 )
 
+// Box is a rectangle by its top left corner and size.
 type Box struct {
 	X, Y, W, H float64
 }
 
+// NewBox returns the box from start to end.
 func NewBox(start, end Point) Box {
 	return Box{start.X, start.Y, end.X - start.X, end.Y - start.Y}
 }
 
+// Values returns the rounded position and size of the box.
 func (b Box) Values() (float64, float64, float64, float64) {
 	return math.Round(b.X), math.Round(b.Y), math.Round(b.W), math.Round(b.H)
 }
 
+// Start returns the top left corner of the box.
 func (b Box) Start() (int, int) {
 	x, y, _, _ := b.Values()
 	return int(x), int(y)
 }
 
+// End returns the bottom right corner of the box.
 func (b Box) End() (int, int) {
 	x, y, w, h := b.Values()
 	return int(x + w), int(y + h)
 }
 
+// Size returns the width and height of the box.
 func (b Box) Size() (int, int) {
 	_, _, w, h := b.Values()
 	return int(w), int(h)
 }
 
+// Rect returns the box as an image.Rectangle.
 func (b Box) Rect() image.Rectangle {
 	x, y, w, h := b.Values()
 	return image.Rect(int(x), int(y), int(x+w), int(y+h))
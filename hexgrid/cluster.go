@@ -0,0 +1,265 @@
+package hexgrid
+
+import (
+	"math"
+	"slices"
+)
+
+// Cells returns the centres of the cells the hexagon covers: its centre and
+// the Size-1 rings of cells around it.
+func (h Hexagon) Cells() []Point {
+	cells := []Point{h.Center}
+	if h.Size <= 1 {
+		return cells
+	}
+
+	grid := NewHexGrid(h)
+	grid.MarkOccupied(h.Center)
+	ring := cells
+	for range h.Size - 1 {
+		var next []Point
+		for _, c := range ring {
+			cell := h
+			cell.Center = c
+			for _, n := range cell.Neiboors() {
+				if grid.IsOccupied(n) {
+					continue
+				}
+				grid.MarkOccupied(n)
+				next = append(next, n)
+			}
+		}
+		cells = append(cells, next...)
+		ring = next
+	}
+	return cells
+}
+
+// Extent returns the radius of a single hexagon as wide as the hexagon.
+func (h Hexagon) Extent() float64 {
+	return h.Radius * float64(2*max(h.Size, 1)-1)
+}
+
+// TopCell returns the topmost cell of the hexagon. Of the cells sharing the
+// top, the one closest to the middle is used, the right one on a tie.
+func (h Hexagon) TopCell() Hexagon {
+	cells := h.Cells()
+	top := cells[0]
+	for _, c := range cells[1:] {
+		dx, topDx := math.Abs(c.X-h.Center.X), math.Abs(top.X-h.Center.X)
+		switch {
+		case c.Y < top.Y-1e-6:
+			top = c
+		case c.Y < top.Y+1e-6 && (dx < topDx-1e-6 || dx < topDx+1e-6 && c.X > top.X):
+			top = c
+		}
+	}
+
+	cell := NewHexagon(top.X, top.Y, h.Radius, h.Angle)
+	cell.Gap, cell.Corner = h.Gap, h.Corner
+	return cell
+}
+
+// clusterOutline returns the outline of the cells of the hexagon merged into
+// one shape, with the gaps between its cells filled in.
+func (h Hexagon) clusterOutline() Polygon {
+	cells := h.Cells()
+	grid := NewHexGrid(h)
+	for _, c := range cells {
+		grid.MarkOccupied(c)
+	}
+
+	// Cells grown by half the gap touch, their outer edges chain into the
+	// outline of the cluster
+	grown := h.Radius + h.Gap/(2*math.Cos(math.Pi/6))
+	key := func(p Point) Point { return Point{math.Round(p.X * 1024), math.Round(p.Y * 1024)} }
+	next := make(map[Point]Point)
+	var start Point
+	for _, c := range cells {
+		cell := h
+		cell.Center = c
+		hex := NewHexagon(c.X, c.Y, grown, h.Angle)
+		for e, n := range cell.Neiboors() {
+			if grid.IsOccupied(n) {
+				continue
+			}
+			if len(next) == 0 {
+				start = hex.Points[e]
+			}
+			next[key(hex.Points[e])] = hex.Points[(e+1)%6]
+		}
+	}
+
+	poly := Polygon{start}
+	for p := next[key(start)]; key(p) != key(start) && len(poly) < len(next); p = next[key(p)] {
+		poly = append(poly, p)
+	}
+
+	if h.Gap != 0 {
+		poly = offsetPolygon(poly, h.Gap/2)
+	}
+	if c := h.corner(); c > 0 {
+		poly = roundCorners(poly, c)
+	}
+	return poly
+}
+
+// winding returns 1 when the points of the polygon go counter-clockwise in
+// x-right y-up axes, -1 otherwise.
+func winding(poly Polygon) float64 {
+	area := 0.0
+	for i, p := range poly {
+		q := poly[(i+1)%len(poly)]
+		area += p.X*q.Y - q.X*p.Y
+	}
+	if area < 0 {
+		return -1
+	}
+	return 1
+}
+
+// inwardNormal returns the unit normal of the edge from a to b pointing to
+// the inside of a polygon with winding w.
+func inwardNormal(a, b Point, w float64) Point {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	l := math.Hypot(dx, dy)
+	return Point{-dy / l * w, dx / l * w}
+}
+
+// miter returns the point where the edges around corner i of the polygon
+// meet once both are moved inwards by d.
+func miter(poly Polygon, i int, w, d float64) (Point, Point, Point) {
+	n := len(poly)
+	p := poly[i]
+	n1 := inwardNormal(poly[(i+n-1)%n], p, w)
+	n2 := inwardNormal(p, poly[(i+1)%n], w)
+	k := d / (1 + n1.X*n2.X + n1.Y*n2.Y)
+	return Point{p.X + (n1.X+n2.X)*k, p.Y + (n1.Y+n2.Y)*k}, n1, n2
+}
+
+// offsetPolygon moves the edges of the polygon inwards by d, or outwards
+// when d is negative.
+func offsetPolygon(poly Polygon, d float64) Polygon {
+	w := winding(poly)
+	out := make(Polygon, len(poly))
+	for i := range poly {
+		p, _, _ := miter(poly, i, w, d)
+		out[i] = NewPoint(snap(p.X), snap(p.Y))
+	}
+	return out
+}
+
+// roundCorners replaces the convex corners of the polygon by arcs of radius
+// c. Concave corners stay sharp, like the notches between rounded hexagons.
+func roundCorners(poly Polygon, c float64) Polygon {
+	w := winding(poly)
+	steps := 2 * int(math.Min(math.Max(c/2, 2), 16))
+
+	var out Polygon
+	for i, p := range poly {
+		o, n1, n2 := miter(poly, i, w, c)
+		if n1.X*n2.Y-n1.Y*n2.X == 0 || (n1.X*n2.Y-n1.Y*n2.X)*w < 0 {
+			out = append(out, p)
+			continue
+		}
+
+		// The arc touches both edges where their normals through o meet them
+		from := math.Atan2(-n1.Y, -n1.X)
+		sweep := math.Remainder(math.Atan2(-n2.Y, -n2.X)-from, 2*math.Pi)
+		for s := range steps + 1 {
+			phi := from + sweep*float64(s)/float64(steps)
+			out = append(out, NewPoint(snap(o.X+c*math.Cos(phi)), snap(o.Y+c*math.Sin(phi))))
+		}
+	}
+	return out
+}
+
+// GenerateScaled generates a hexagon of the cell for each size around (x, y),
+// covering size rings of cells. Hexagons are placed in order, each in the
+// free spot closest to the centre, so later single cells fill the space
+// left around bigger ones. Hexagon i always has size i.
+func GenerateScaled(sizes []int, x, y float64, cell Cell) []Hexagon {
+	total, largest := 0, 1
+	for _, k := range sizes {
+		k = max(k, 1)
+		total += 3*k*(k-1) + 1
+		largest = max(largest, k)
+	}
+
+	// Enough room for twice the rings the cells would fill when packed
+	rings := 2*int(math.Ceil(math.Sqrt(float64(total)/3))) + 2*largest
+	return generateScaled(sizes, x, y, cell, rings)
+}
+
+// generateScaled places the hexagons of GenerateScaled, searching rings of
+// cells around the centre first and further out when a hexagon doesn't fit.
+func generateScaled(sizes []int, x, y float64, cell Cell, rings int) []Hexagon {
+	if len(sizes) == 0 {
+		return nil
+	}
+
+	centre := cell.Hexagon(x, y)
+	area := centre
+	var candidates []Point
+	search := func(size int) {
+		area.Size = size
+		candidates = area.Cells()
+		slices.SortStableFunc(candidates, func(i, j Point) int { return compareAround(centre.Center, i, j) })
+	}
+	search(max(rings, 1))
+
+	grid := NewHexGrid(centre)
+	hexs := make([]Hexagon, 0, len(sizes))
+	first := 0
+	for _, k := range sizes {
+		for {
+			for first < len(candidates) && grid.IsOccupied(candidates[first]) {
+				first++
+			}
+
+			i := slices.IndexFunc(candidates[first:], func(c Point) bool {
+				hex := cell.Hexagon(c.X, c.Y)
+				hex.Size = k
+				return !slices.ContainsFunc(hex.Cells(), grid.IsOccupied)
+			})
+			if i >= 0 {
+				c := candidates[first+i]
+				hex := cell.Hexagon(c.X, c.Y)
+				hex.Size = k
+				for _, p := range hex.Cells() {
+					grid.MarkOccupied(p)
+				}
+				hexs = append(hexs, hex)
+				break
+			}
+
+			// Nothing fits in the area, the cells outside of it are free
+			search(2 * area.Size)
+			first = 0
+		}
+	}
+
+	return hexs
+}
+
+// NodeSizes returns the size of the hexagon of each node: 3 for the user and
+// 2 for favourites and the entries with the best score the user gave.
+func NodeSizes(nodes []Node) []int {
+	best := 0.0
+	for _, node := range nodes {
+		best = math.Max(best, node.UserScore)
+	}
+
+	sizes := make([]int, len(nodes))
+	for i, node := range nodes {
+		switch {
+		case node.Type == UserNode:
+			sizes[i] = 3
+		case node.Favourite, best > 0 && node.UserScore == best:
+			sizes[i] = 2
+		default:
+			sizes[i] = 1
+		}
+	}
+	return sizes
+}
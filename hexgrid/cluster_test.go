@@ -0,0 +1,179 @@
+package hexgrid
+
+import (
+	"math"
+	"testing"
+)
+
+// polygonArea returns the area of the polygon.
+func polygonArea(poly Polygon) float64 {
+	area := 0.0
+	for i, p := range poly {
+		q := poly[(i+1)%len(poly)]
+		area += p.X*q.Y - q.X*p.Y
+	}
+	return math.Abs(area) / 2
+}
+
+func TestHexagonCells(t *testing.T) {
+	for _, o := range []Orientation{Flat, Pointy} {
+		hex := Cell{Radius: 20, Orientation: o, Gap: 4}.Hexagon(100, 100)
+		for size, want := range map[int]int{0: 1, 1: 1, 2: 7, 3: 19} {
+			hex.Size = size
+			if got := len(hex.Cells()); got != want {
+				t.Errorf("Expected %d cells for a %s size %d hexagon, got %d", want, o, size, got)
+			}
+		}
+	}
+}
+
+func TestClusterOutline(t *testing.T) {
+	for _, o := range []Orientation{Flat, Pointy} {
+		hex := Cell{Radius: 20, Orientation: o}.Hexagon(100, 100)
+		single := polygonArea(hex.Outline())
+
+		hex.Size = 2
+		outline := hex.Outline()
+		if len(outline) != 18 {
+			t.Errorf("Expected 18 corners around 7 %s cells, got %d", o, len(outline))
+		}
+		if area := polygonArea(outline); math.Abs(area-7*single) > 1e-3 {
+			t.Errorf("Expected the area of 7 %s cells, got %f for %f", o, area, 7*single)
+		}
+
+		// The gaps between the cells are filled, the gap around them isn't
+		hex.Gap = 6
+		spaced := hex.Outline()
+		for _, c := range hex.Cells() {
+			if !spaced.Contains(int(c.X), int(c.Y)) {
+				t.Errorf("Expected the %s outline to contain cell %v", o, c)
+			}
+		}
+		// Moving the edges of the 12 convex and 6 concave corners in by d
+		// takes away the perimeter times d and gives back (12-6)·tan(30°)·d²
+		d := hex.Gap / 2
+		grown := NewHexagon(100, 100, hex.Radius+d/math.Cos(math.Pi/6), hex.Angle)
+		grown.Size = 2
+		want := polygonArea(grown.Outline()) - 18*grown.Side()*d + 6*math.Tan(math.Pi/6)*d*d
+		if area := polygonArea(spaced); math.Abs(area-want) > 1e-3 {
+			t.Errorf("Expected the %s outline to leave half the gap around it, got area %f for %f", o, area, want)
+		}
+
+		// Insets keep the cells in place
+		inset := hex.Inset(3)
+		if inset.Spacing() != hex.Spacing() {
+			t.Errorf("Expected the inset to keep the spacing %f, got %f", hex.Spacing(), inset.Spacing())
+		}
+		if area := polygonArea(inset.Outline()); area >= polygonArea(spaced) {
+			t.Errorf("Expected the inset %s outline to be smaller", o)
+		}
+
+		hex.Corner = 5
+		if rounded := hex.Outline(); polygonArea(rounded) >= polygonArea(spaced) {
+			t.Errorf("Expected rounded %s corners to cut the outline", o)
+		}
+	}
+}
+
+func TestTopCell(t *testing.T) {
+	hex := Cell{Radius: 20, Orientation: Pointy}.Hexagon(100, 100)
+	hex.Size = 2
+	top := hex.TopCell()
+	if top.Center.Y >= hex.Center.Y || top.Center.X <= hex.Center.X {
+		t.Errorf("Expected the right cell of the top row, got %v", top.Center)
+	}
+
+	hex.Size = 3
+	if top := hex.TopCell(); math.Abs(top.Center.X-hex.Center.X) > 1e-6 {
+		t.Errorf("Expected the middle cell of the top row, got %v", top.Center)
+	}
+}
+
+func TestGenerateScaled(t *testing.T) {
+	sizes := []int{3, 2, 1, 2}
+	for range 40 {
+		sizes = append(sizes, 1)
+	}
+
+	cell := Cell{Radius: 20, Gap: 2}
+	hexs := GenerateScaled(sizes, 500, 500, cell)
+	if len(hexs) != len(sizes) {
+		t.Fatalf("Expected %d hexagons, got %d", len(sizes), len(hexs))
+	}
+	if hexs[0].Center != (Point{500, 500}) || hexs[0].Size != 3 {
+		t.Errorf("Expected the biggest hexagon in the centre, got %v", hexs[0].Center)
+	}
+
+	grid := NewHexGrid(hexs[0])
+	for i, hex := range hexs {
+		if hex.Size != sizes[i] {
+			t.Errorf("Expected hexagon %d of size %d, got %d", i, sizes[i], hex.Size)
+		}
+		for _, c := range hex.Cells() {
+			if grid.IsOccupied(c) {
+				t.Fatalf("Expected hexagon %d not to overlap others at %v", i, c)
+			}
+			grid.MarkOccupied(c)
+		}
+	}
+
+	// The rings around the centre are packed without holes
+	area := hexs[0]
+	area.Size = 5
+	for _, c := range area.Cells() {
+		if !grid.IsOccupied(c) {
+			t.Errorf("Expected cell %v to be filled", c)
+		}
+	}
+
+	rings := Rings(hexs)
+	if len(rings[0]) != 1 || rings[0][0] != 0 {
+		t.Errorf("Expected the centre alone in the first ring, got %v", rings[0])
+	}
+	count := 0
+	for _, ring := range rings {
+		count += len(ring)
+	}
+	if count != len(hexs) || len(rings[1]) < 6 {
+		t.Errorf("Expected the hexagons around the centre in the second ring, got %v", rings)
+	}
+}
+
+func TestGenerateScaledGrowsArea(t *testing.T) {
+	// A single ring around the centre can't hold any of them
+	sizes := []int{3, 1, 4, 2, 1, 3}
+	hexs := generateScaled(sizes, 0, 0, Cell{Radius: 10}, 1)
+	if len(hexs) != len(sizes) {
+		t.Fatalf("Expected %d hexagons, got %d", len(sizes), len(hexs))
+	}
+
+	grid := NewHexGrid(hexs[0])
+	for i, hex := range hexs {
+		if hex.Size != sizes[i] {
+			t.Errorf("Expected hexagon %d of size %d, got %d", i, sizes[i], hex.Size)
+		}
+		for _, c := range hex.Cells() {
+			if grid.IsOccupied(c) {
+				t.Fatalf("Expected hexagon %d not to overlap others at %v", i, c)
+			}
+			grid.MarkOccupied(c)
+		}
+	}
+}
+
+func TestNodeSizes(t *testing.T) {
+	nodes := []Node{
+		{Type: UserNode},
+		{Type: AnimeNode, UserScore: 10},
+		{Type: AnimeNode, UserScore: 9, Favourite: true},
+		{Type: MangaNode, UserScore: 9},
+		{Type: CharacterNode},
+	}
+
+	want := []int{3, 2, 2, 1, 1}
+	for i, size := range NodeSizes(nodes) {
+		if size != want[i] {
+			t.Errorf("Expected node %d to be of size %d, got %d", i, want[i], size)
+		}
+	}
+}
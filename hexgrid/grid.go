@@ -0,0 +1,175 @@
+package hexgrid
+
+import (
+	"fmt"
+	"math"
+)
+
+// Grid keeps track of the cells taken by hexagons.
+type Grid struct {
+	occupied map[string]bool
+	origin   Point
+	step     Point // Size of a cell.
+}
+
+// NewGrid returns an empty grid of cells of radius.
+func NewGrid(radius float64) *Grid {
+	return &Grid{occupied: make(map[string]bool), step: Point{radius, radius}}
+}
+
+// NewHexGrid creates a grid with a cell for each hexagon that fits around
+// hex. The centres of the hexagons fall on the middle of the cells, so
+// rounding errors never move them to another cell.
+func NewHexGrid(hex Hexagon) *Grid {
+	d := hex.Spacing()
+	step := Point{d * math.Cos(math.Pi/6), d / 2}
+	if hex.Pointy() {
+		step = Point{d / 2, d * math.Cos(math.Pi/6)}
+	}
+	return &Grid{occupied: make(map[string]bool), origin: hex.Center, step: step}
+}
+
+// Key returns the key of the cell at x, y.
+func (hg Grid) Key(x, y float64) string {
+	sx := math.Round((x - hg.origin.X) / hg.step.X)
+	sy := math.Round((y - hg.origin.Y) / hg.step.Y)
+	// Integers, rounding to -0 would give another key than 0
+	return fmt.Sprintf("%d,%d", int(sx), int(sy))
+}
+
+// IsOccupied reports whether a hexagon was placed at p.
+func (hg Grid) IsOccupied(p Point) bool {
+	return hg.occupied[hg.Key(p.X, p.Y)]
+}
+
+// MarkOccupied marks the cell at p as taken.
+func (hg *Grid) MarkOccupied(p Point) {
+	hg.occupied[hg.Key(p.X, p.Y)] = true
+}
+
+// Cell is the shape of the hexagons of a grid.
+type Cell struct {
+	Radius      float64
+	Orientation Orientation
+	Gap         float64 // Space between the edges of neighbouring hexagons.
+	Corner      float64 // Radius of the rounded corners.
+}
+
+// Hexagon creates a hexagon of the cell centred at (x, y).
+func (c Cell) Hexagon(x, y float64) Hexagon {
+	hex := NewHexagon(x, y, c.Radius, c.Orientation.Angle())
+	hex.Gap = c.Gap
+	hex.Corner = c.Corner
+	return hex
+}
+
+// GenerateHexagonRing generates a grid of flat hexagons touching each other.
+func GenerateHexagonRing(n int, x, y, radius float64) []Hexagon {
+	return GenerateGrid(n, x, y, Cell{Radius: radius, Orientation: Flat})
+}
+
+// GenerateGrid generates hexagons of the cell around (x, y), sorted by their
+// distance from it.
+func GenerateGrid(n int, x, y float64, cell Cell) []Hexagon {
+	if n <= 0 {
+		return nil
+	}
+
+	centerHex := cell.Hexagon(x, y)
+	full := NewHexGrid(centerHex)
+	empty := make(map[Point]bool)
+
+	full.MarkOccupied(centerHex.Center)
+	hexagons := []Hexagon{centerHex}
+
+	for _, p := range centerHex.Neiboors() {
+		empty[p] = true
+	}
+
+	for range n - 1 {
+		found := false
+		hexCenter := centerHex.Center
+		for point := range empty {
+			// Ties go clockwise from the top, whatever the order of the map
+			if !found || compareAround(centerHex.Center, point, hexCenter) < 0 {
+				hexCenter = point
+				found = true
+			}
+		}
+
+		hex := cell.Hexagon(hexCenter.X, hexCenter.Y)
+		hexagons = append(hexagons, hex)
+
+		full.MarkOccupied(hexCenter)
+		for _, p := range hex.Neiboors() {
+			if !full.IsOccupied(p) {
+				empty[p] = true
+			}
+		}
+
+		for p := range empty {
+			if full.IsOccupied(p) {
+				delete(empty, p)
+			}
+		}
+
+	}
+
+	SortHexagons(hexagons, centerHex.Center)
+	return hexagons
+}
+
+// Rings groups the hexagons by their distance in steps from the first one,
+// which is the centre of a ring grid. Hexagons keep their order inside a ring.
+// Hexagons covering several cells neighbour the hexagons around any of them.
+func Rings(hexs []Hexagon) [][]int {
+	if len(hexs) == 0 {
+		return nil
+	}
+
+	grid := NewHexGrid(hexs[0])
+	index := make(map[string]int, len(hexs))
+	for i, hex := range hexs {
+		for _, c := range hex.Cells() {
+			index[grid.Key(c.X, c.Y)] = i
+		}
+	}
+
+	ring := make([]int, len(hexs))
+	for i := range ring {
+		ring[i] = -1
+	}
+	ring[0] = 0
+
+	queue := []int{0}
+	last := 0
+	for len(queue) != 0 {
+		i := queue[0]
+		queue = queue[1:]
+
+		for _, c := range hexs[i].Cells() {
+			cell := hexs[i]
+			cell.Center = c
+			for _, p := range cell.Neiboors() {
+				j, ok := index[grid.Key(p.X, p.Y)]
+				if !ok || ring[j] != -1 {
+					continue
+				}
+				ring[j] = ring[i] + 1
+				last = max(last, ring[j])
+				queue = append(queue, j)
+			}
+		}
+	}
+
+	rings := make([][]int, last+1)
+	for i, r := range ring {
+		if r == -1 {
+			// Not connected to the centre, reveal it last
+			r = last
+		}
+		rings[r] = append(rings[r], i)
+	}
+
+	return rings
+}
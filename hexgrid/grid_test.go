@@ -0,0 +1,39 @@
+package hexgrid
+
+import "testing"
+
+func TestGrid(t *testing.T) {
+	radius := 10.0
+	grid := NewGrid(radius)
+	point := Point{X: 15.3, Y: 25.7}
+
+	if grid.IsOccupied(point) {
+		t.Errorf("Expected IsOccupied() to return false, got true")
+	}
+
+	grid.MarkOccupied(point)
+
+	if !grid.IsOccupied(point) {
+		t.Errorf("Expected IsOccupied() to return true after MarkOccupied, got false")
+	}
+}
+
+func TestRings(t *testing.T) {
+	hexs := GenerateHexagonRing(19, 500, 500, 50)
+	rings := Rings(hexs)
+
+	if len(rings) != 3 {
+		t.Fatalf("Expected 3 rings, got %d", len(rings))
+	}
+
+	expected := []int{1, 6, 12}
+	for i, ring := range rings {
+		if len(ring) != expected[i] {
+			t.Errorf("Expected %d hexagons in ring %d, got %d", expected[i], i, len(ring))
+		}
+	}
+
+	if rings[0][0] != 0 {
+		t.Errorf("Expected the first hexagon in the centre ring")
+	}
+}
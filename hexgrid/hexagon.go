@@ -0,0 +1,209 @@
+// Package hexgrid places hexagons on an image: rings, sectors, rectangles
+// and shaped grids, and the order the nodes of a grid are given to them.
+package hexgrid
+
+import (
+	"math"
+	"slices"
+
+	"github.com/fogleman/gg"
+)
+
+// Orientation is the direction the corners of the hexagons of a grid point to.
+type Orientation string
+
+const (
+	Flat   Orientation = "flat"   // Flat edges at the top and bottom.
+	Pointy Orientation = "pointy" // Corners at the top and bottom.
+)
+
+// Angle returns the rotation of hexagons with the orientation.
+func (o Orientation) Angle() float64 {
+	if o == Pointy {
+		return math.Pi / 6
+	}
+	return 0
+}
+
+// Define Hexagon as an array of 6 Points
+type Hexagon struct {
+	Points [6]Point
+	Center Point
+	Angle  float64
+	Radius float64
+	Gap    float64 // Space between the edges of neighbouring hexagons.
+	Corner float64 // Radius of the rounded corners, 0 for sharp ones.
+	Size   int     // Cells along each side of a cluster of cells, 0 or 1 for one cell.
+}
+
+// NewHexagon creates a hexagon centered at (x, y) with a given radius and rotation angle
+func NewHexagon(x, y, radius, angle float64) Hexagon {
+	center := Point{x, y}
+
+	hex := Hexagon{
+		Center: center,
+		Radius: radius,
+		Angle:  angle,
+	}
+	// Compute 6 vertices of the hexagon
+	for i := range 6 {
+		// 60-degree increments (π/3 radians)
+		theta := angle + float64(i)*(math.Pi/3)
+		hex.Points[i] = NewPoint(snap(x+radius*math.Cos(theta)), snap(y+radius*math.Sin(theta)))
+	}
+
+	return hex
+}
+
+// snap rounds v to a 2^-24 of a pixel, far coarser than floating point
+// errors, so neighbours computed from different centres share their corners
+// exactly.
+func snap(v float64) float64 {
+	return math.Round(v*(1<<24)) / (1 << 24)
+}
+
+// Draw adds the outline of the hexagon to the path of ctx.
+func (h Hexagon) Draw(ctx *gg.Context) {
+	outline := h.Outline()
+
+	ctx.ClearPath()
+	ctx.MoveTo(outline[0].Value())
+	for _, p := range outline[1:] {
+		ctx.LineTo(p.Value())
+	}
+	ctx.ClosePath()
+}
+
+// Outline returns the shape of the hexagon as a polygon. Rounded corners
+// are approximated by short segments. The same outline clips the image,
+// fills placeholders and is stroked.
+func (h Hexagon) Outline() Polygon {
+	if h.Size > 1 {
+		return h.clusterOutline()
+	}
+	if h.corner() == 0 {
+		return Polygon(h.Points[:])
+	}
+
+	var poly Polygon
+	for i := range 6 {
+		edge := h.Edge(i)
+		poly = append(poly, edge[:len(edge)-1]...)
+	}
+	return poly
+}
+
+// corner returns the radius of the corners, limited to the inscribed circle.
+func (h Hexagon) corner() float64 {
+	return math.Max(0, math.Min(h.Corner, h.Radius*math.Cos(math.Pi/6)))
+}
+
+// Edge returns the path of edge i, from corner i to corner i+1. With rounded
+// corners the path starts and ends in the middle of the corner arcs. Edge i
+// faces neighbour i of Neiboors.
+func (h Hexagon) Edge(i int) []Point {
+	c := h.corner()
+	if c == 0 {
+		return []Point{h.Points[i], h.Points[(i+1)%6]}
+	}
+
+	// Arcs are centred on the way from the corner to the centre, where the
+	// circle touches both edges
+	steps := int(math.Min(math.Max(c/2, 2), 16))
+	arc := func(i int, from, to float64) []Point {
+		theta := h.Angle + float64(i)*(math.Pi/3)
+		d := h.Radius - c/math.Sin(math.Pi/3)
+		cx, cy := h.Center.X+d*math.Cos(theta), h.Center.Y+d*math.Sin(theta)
+
+		points := make([]Point, 0, steps+1)
+		for s := range steps + 1 {
+			phi := theta + from + (to-from)*float64(s)/float64(steps)
+			points = append(points, NewPoint(snap(cx+c*math.Cos(phi)), snap(cy+c*math.Sin(phi))))
+		}
+		return points
+	}
+
+	return append(arc(i, 0, math.Pi/6), arc((i+1)%6, -math.Pi/6, 0)...)
+}
+
+// Inset returns the hexagon with its edges moved inwards by d, or outwards
+// when d is negative. Rounded corners keep following the edges and the gap
+// grows so the spacing of the cells stays the same.
+func (h Hexagon) Inset(d float64) Hexagon {
+	inset := NewHexagon(h.Center.X, h.Center.Y, h.Radius-d/math.Cos(math.Pi/6), h.Angle)
+	inset.Gap = h.Gap + 2*d
+	inset.Size = h.Size
+	if h.Corner > 0 {
+		inset.Corner = math.Max(h.Corner-d, 0)
+	}
+	return inset
+}
+
+// Side returns the length of a side of the hexagon.
+func (h Hexagon) Side() float64 {
+	return h.Points[0].Distance(h.Points[1])
+}
+
+// Box returns the bounding box of the hexagon.
+func (h Hexagon) Box() Box {
+	points := h.Points[:]
+	if h.Size > 1 {
+		points = h.Outline()
+	}
+
+	xSlice := make([]float64, len(points))
+	ySlice := make([]float64, len(points))
+	for i, p := range points {
+		xSlice[i] = p.X
+		ySlice[i] = p.Y
+	}
+
+	start := NewPoint(Min(xSlice[0], xSlice[1:]...), Min(ySlice[0], ySlice[1:]...))
+	end := NewPoint(Max(xSlice[0], xSlice[1:]...), Max(ySlice[0], ySlice[1:]...))
+
+	return NewBox(start, end)
+}
+
+// Spacing returns the distance between the centres of neighbouring hexagons.
+func (h Hexagon) Spacing() float64 {
+	return h.Radius*math.Cos(math.Pi/6)*2 + h.Gap
+}
+
+// Pointy reports whether the hexagon has corners at the top and bottom.
+func (h Hexagon) Pointy() bool {
+	return math.Abs(math.Mod(h.Angle, math.Pi/3)-math.Pi/6) < 1e-9
+}
+
+// Neiboors returns the centres of the six neighbouring hexagons.
+func (h Hexagon) Neiboors() []Point {
+	ringRadius := h.Spacing()
+	n := make([]Point, 6)
+
+	for i := range 6 {
+		angle := h.Angle + (math.Pi / 6) + (float64(i) * (math.Pi / 3))
+		n[i] = NewPoint(h.Center.X+ringRadius*math.Cos(angle), h.Center.Y+ringRadius*math.Sin(angle))
+	}
+	return n
+}
+
+// Min returns the smallest of its arguments.
+func Min(a float64, b ...float64) float64 {
+	r := a
+	for v := range slices.Values(b) {
+		if v < r {
+			r = v
+		}
+	}
+	return r
+}
+
+// Max returns the largest of its arguments.
+func Max(a float64, b ...float64) float64 {
+	r := a
+	for v := range slices.Values(b) {
+		if v > r {
+			r = v
+		}
+	}
+	return r
+}
@@ -0,0 +1,131 @@
+package hexgrid
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewHexagon(t *testing.T) {
+	h := NewHexagon(0, 0, 10, 0)
+
+	if h.Center.X != 0 || h.Center.Y != 0 {
+		t.Errorf("Expected center (0,0), got (%f,%f)", h.Center.X, h.Center.Y)
+	}
+
+	if h.Radius != 10 {
+		t.Errorf("Expected radius 10, got %f", h.Radius)
+	}
+}
+
+func TestHexagonSide(t *testing.T) {
+	h := NewHexagon(0, 0, 10, 0)
+	side := h.Side()
+	expected := 10.0 // Since it's an equilateral hexagon
+
+	if math.Abs(side-expected) > 1e-6 {
+		t.Errorf("Expected side length %f, got %f", expected, side)
+	}
+}
+
+func TestHexagonOrientation(t *testing.T) {
+	flat := NewHexagon(0, 0, 10, Flat.Angle())
+	if flat.Pointy() || flat.Box().H >= flat.Box().W {
+		t.Errorf("Expected flat hexagon to be wider than tall, got %+v", flat.Box())
+	}
+
+	pointy := NewHexagon(0, 0, 10, Pointy.Angle())
+	if !pointy.Pointy() || pointy.Box().H <= pointy.Box().W {
+		t.Errorf("Expected pointy hexagon to be taller than wide, got %+v", pointy.Box())
+	}
+
+	side := pointy.Side()
+	if math.Abs(side-10) > 1e-6 {
+		t.Errorf("Expected side length 10, got %f", side)
+	}
+}
+
+func TestHexagonNeighbors(t *testing.T) {
+	for _, orientation := range []Orientation{Flat, Pointy} {
+		for _, gap := range []float64{0, 4} {
+			h := Cell{Radius: 10, Orientation: orientation, Gap: gap}.Hexagon(0, 0)
+			n := h.Neiboors()
+
+			if len(n) != 6 {
+				t.Errorf("Expected 6 neighbors, got %d", len(n))
+			}
+
+			// Neighbours share an edge, moved apart by the gap
+			expectedDistance := (h.Radius*math.Cos(math.Pi/6))*2 + gap
+
+			for _, p := range n {
+				d := h.Center.Distance(p)
+				if math.Abs(d-expectedDistance) > 1e-6 {
+					t.Errorf("%s, gap %.0f: Expected neighbor distance %f, got %f", orientation, gap, expectedDistance, d)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateGrid(t *testing.T) {
+	for _, orientation := range []Orientation{Flat, Pointy} {
+		for _, gap := range []float64{0, 6} {
+			cell := Cell{Radius: 20, Orientation: orientation, Gap: gap}
+			hexs := GenerateGrid(19, 200, 200, cell)
+
+			if len(hexs) != 19 {
+				t.Fatalf("%s, gap %.0f: Expected 19 hexagons, got %d", orientation, gap, len(hexs))
+			}
+
+			spacing := hexs[0].Spacing()
+			for i, a := range hexs {
+				if a.Angle != orientation.Angle() {
+					t.Errorf("%s: Expected hexagon %d to be rotated by %f, got %f", orientation, i, orientation.Angle(), a.Angle)
+				}
+				for _, b := range hexs[i+1:] {
+					if d := a.Center.Distance(b.Center); d < spacing-1e-6 {
+						t.Errorf("%s, gap %.0f: Expected hexagons at least %f apart, got %f", orientation, gap, spacing, d)
+					}
+				}
+			}
+
+			if rings := Rings(hexs); len(rings) != 3 {
+				t.Errorf("%s, gap %.0f: Expected 3 rings, got %d", orientation, gap, len(rings))
+			}
+		}
+	}
+}
+
+func TestHexagonRoundedOutline(t *testing.T) {
+	sharp := NewHexagon(50, 50, 40, 0)
+	rounded := sharp
+	rounded.Corner = 10
+
+	outline := rounded.Outline()
+	if len(outline) <= 6 {
+		t.Fatalf("Expected arcs in the rounded outline, got %d points", len(outline))
+	}
+
+	// The corner at the right is cut, the middle of the edges stays
+	if !sharp.Outline().Contains(88, 50) || rounded.Outline().Contains(88, 50) {
+		t.Errorf("Expected the rounded corner to cut off the sharp tip")
+	}
+	if !rounded.Outline().Contains(50, 15) {
+		t.Errorf("Expected the middle of the top edge inside the rounded outline")
+	}
+}
+
+func TestHexagonInset(t *testing.T) {
+	hex := NewHexagon(0, 0, 20, 0)
+	inset := hex.Inset(2)
+
+	// The apothem shrinks by the inset
+	apothem := func(h Hexagon) float64 { return h.Radius * math.Cos(math.Pi/6) }
+	if d := apothem(hex) - apothem(inset); math.Abs(d-2) > 1e-9 {
+		t.Errorf("Expected edges 2px further in, got %f", d)
+	}
+
+	if outset := hex.Inset(-3); outset.Radius <= hex.Radius {
+		t.Errorf("Expected a negative inset to grow the hexagon")
+	}
+}
@@ -0,0 +1,141 @@
+package hexgrid
+
+import (
+	"errors"
+	"math"
+	"slices"
+)
+
+// Layout decides which hexagon of the grid each node goes in.
+type Layout string
+
+const (
+	// LayoutRings fills the grid from the centre out by score.
+	LayoutRings Layout = "rings"
+	// LayoutSectors splits the grid into a wedge per node type, sized by the
+	// number of nodes of the type, with the user in the centre.
+	LayoutSectors Layout = "sectors"
+	// LayoutRect tiles a whole rectangle, repeating nodes when there are
+	// more hexagons than nodes.
+	LayoutRect Layout = "rect"
+)
+
+// Arrange orders nodes so node i goes in hexagon i. Nodes must be sorted by
+// score and hexagons by their distance from the centre.
+func (l Layout) Arrange(hexs []Hexagon, nodes []Node) []Node {
+	switch l {
+	case LayoutSectors:
+		return arrangeSectors(hexs, nodes)
+	default:
+		return nodes
+	}
+}
+
+// ErrNotEnoughNodes is returned when there are fewer nodes than hexagons and
+// none of them can be repeated to fill the rest.
+var ErrNotEnoughNodes = errors.New("not enough nodes to fill the grid")
+
+// RepeatNodes repeats nodes in order until there are n of them. The user
+// is never repeated, without other nodes it returns ErrNotEnoughNodes.
+func RepeatNodes(nodes []Node, n int) ([]Node, error) {
+	if len(nodes) >= n {
+		return nodes, nil
+	}
+	others := slices.DeleteFunc(slices.Clone(nodes), func(n Node) bool { return n.Type == UserNode })
+	if len(others) == 0 {
+		return nodes, ErrNotEnoughNodes
+	}
+
+	repeated := slices.Grow(slices.Clone(nodes), n-len(nodes))
+	for i := 0; len(repeated) < n; i++ {
+		repeated = append(repeated, others[i%len(others)])
+	}
+	return repeated, nil
+}
+
+// sector is the wedge of the grid holding the nodes of one type.
+type sector struct {
+	start, end float64 // Angles of the wedge, clockwise from the top.
+	nodes      []Node
+}
+
+// distance returns how far angle a is from the wedge, 0 when inside.
+func (s sector) distance(a float64) float64 {
+	if a >= s.start && a < s.end {
+		return 0
+	}
+	return math.Min(angleBetween(a, s.start), angleBetween(a, s.end))
+}
+
+// angleBetween returns the smaller angle between a and b.
+func angleBetween(a, b float64) float64 {
+	d := math.Mod(math.Abs(a-b), 2*math.Pi)
+	return math.Min(d, 2*math.Pi-d)
+}
+
+func arrangeSectors(hexs []Hexagon, nodes []Node) []Node {
+	if len(hexs) == 0 || len(nodes) == 0 {
+		return nodes
+	}
+
+	// The user, or the best node without one, takes the centre
+	centre := slices.IndexFunc(nodes, func(n Node) bool { return n.Type == UserNode })
+	if centre < 0 {
+		centre = 0
+	}
+
+	byType := make(map[NodeType][]Node)
+	var types []NodeType
+	for i, node := range nodes {
+		if i == centre {
+			continue
+		}
+		if _, ok := byType[node.Type]; !ok {
+			types = append(types, node.Type)
+		}
+		byType[node.Type] = append(byType[node.Type], node)
+	}
+	slices.Sort(types)
+
+	rest := len(nodes) - 1
+	sectors := make([]*sector, len(types))
+	start := 0.0
+	for i, t := range types {
+		end := start + 2*math.Pi*float64(len(byType[t]))/float64(rest)
+		sectors[i] = &sector{start: start, end: end, nodes: byType[t]}
+		start = end
+	}
+
+	arranged := make([]Node, 0, len(nodes))
+	arranged = append(arranged, nodes[centre])
+
+	origin := hexs[0].Center
+	for _, hex := range hexs[1:] {
+		// Clockwise from the top, y grows downwards
+		a := math.Atan2(hex.Center.X-origin.X, origin.Y-hex.Center.Y)
+		if a < 0 {
+			a += 2 * math.Pi
+		}
+
+		// Full wedges overflow into the closest one that still has nodes
+		var best *sector
+		for _, s := range sectors {
+			if len(s.nodes) != 0 && (best == nil || s.distance(a) < best.distance(a)) {
+				best = s
+			}
+		}
+		if best == nil {
+			break
+		}
+
+		arranged = append(arranged, best.nodes[0])
+		best.nodes = best.nodes[1:]
+	}
+
+	// Nodes without a hexagon keep their order at the end
+	for _, s := range sectors {
+		arranged = append(arranged, s.nodes...)
+	}
+
+	return arranged
+}
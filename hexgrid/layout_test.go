@@ -0,0 +1,100 @@
+package hexgrid
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+// layoutNodes returns a user and count nodes of each type, sorted by score.
+func layoutNodes(counts map[NodeType]int) []Node {
+	nodes := []Node{{Type: UserNode, Score: 1 << 60}}
+	for t, n := range counts {
+		for i := range n {
+			nodes = append(nodes, Node{Type: t, Score: 1000 - i*10 - int(t)})
+		}
+	}
+	slices.SortFunc(nodes, func(i, j Node) int { return j.Score - i.Score })
+	return nodes
+}
+
+func TestLayoutRings(t *testing.T) {
+	nodes := layoutNodes(map[NodeType]int{AnimeNode: 5, MangaNode: 5})
+	hexs := GenerateHexagonRing(len(nodes), 200, 200, 20)
+
+	if arranged := LayoutRings.Arrange(hexs, nodes); !slices.Equal(arranged, nodes) {
+		t.Errorf("Expected rings to keep the score order")
+	}
+}
+
+func TestLayoutSectors(t *testing.T) {
+	counts := map[NodeType]int{AnimeNode: 60, MangaNode: 20, CharacterNode: 10}
+	nodes := layoutNodes(counts)
+	hexs := GenerateHexagonRing(len(nodes), 500, 500, 20)
+
+	arranged := LayoutSectors.Arrange(hexs, nodes)
+	if len(arranged) != len(nodes) {
+		t.Fatalf("Expected %d nodes, got %d", len(nodes), len(arranged))
+	}
+	if arranged[0].Type != UserNode {
+		t.Errorf("Expected the user in the centre, got %v", arranged[0].Type)
+	}
+
+	// Better scores sit closer to the centre within each sector
+	last := make(map[NodeType]int)
+	for i, node := range arranged[1:] {
+		if prev, ok := last[node.Type]; ok && node.Score > prev {
+			t.Errorf("Expected hexagon %d to score at most %d, got %d", i+1, prev, node.Score)
+		}
+		last[node.Type] = node.Score
+	}
+
+	// Most anime lands in the first wedge, which spans 2/3 of the circle
+	inside := 0
+	origin := hexs[0].Center
+	for i, node := range arranged[1:] {
+		if node.Type != AnimeNode {
+			continue
+		}
+		c := hexs[i+1].Center
+		a := math.Atan2(c.X-origin.X, origin.Y-c.Y)
+		if a < 0 {
+			a += 2 * math.Pi
+		}
+		if a < 2*math.Pi*60/90+0.3 {
+			inside++
+		}
+	}
+	if inside < 55 {
+		t.Errorf("Expected anime in its wedge, got %d of 60", inside)
+	}
+}
+
+func TestRepeatNodes(t *testing.T) {
+	nodes := layoutNodes(map[NodeType]int{AnimeNode: 2})
+
+	repeated, err := RepeatNodes(nodes, 7)
+	if err != nil || len(repeated) != 7 {
+		t.Fatalf("Expected 7 nodes, got %d, %v", len(repeated), err)
+	}
+	for i, node := range repeated[1:] {
+		if node.Type == UserNode {
+			t.Errorf("Expected the user once, got it again at %d", i+1)
+		}
+	}
+	if repeated[3] != nodes[1] || repeated[4] != nodes[2] {
+		t.Errorf("Expected nodes to repeat in order, got %v", repeated)
+	}
+
+	if got, err := RepeatNodes(nodes, 2); err != nil || len(got) != len(nodes) {
+		t.Errorf("Expected nodes to be kept when there are enough, got %d, %v", len(got), err)
+	}
+
+	// Only the user, or nothing, can't fill the grid
+	for _, nodes := range [][]Node{nil, nodes[:1]} {
+		if _, err := RepeatNodes(nodes, 7); !errors.Is(err, ErrNotEnoughNodes) {
+			t.Errorf("Expected ErrNotEnoughNodes for %d nodes, got %v", len(nodes), err)
+		}
+	}
+}
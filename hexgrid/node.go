@@ -0,0 +1,103 @@
+package hexgrid
+
+import (
+	"cmp"
+	"image/color"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// NodeType is the kind of item a node stands for.
+type NodeType uint64
+
+const (
+	UserNode NodeType = iota
+	AnimeNode
+	MangaNode
+	CharacterNode
+	ImageNode
+)
+
+// Node is a single item placed in a hexagon of the grid.
+type Node struct {
+	Type  NodeType
+	ID    int64  // AniList ID of the user, media or character, 0 for others.
+	Image string // Path or url of the image.
+	Score int
+	Label string // Title of the item.
+	Link  string // Page of the item, if any.
+	Color string // Dominant colour as #rrggbb, used when the image is missing.
+
+	UserScore float64 // Score the user gave the item, 0 when unscored.
+	Status    string  // AniList list status of the item, empty for other nodes.
+	Favourite bool    // Whether the item is one of the user's favourites.
+}
+
+// IsLocal reports whether image, the Image of a node, is a local file rather
+// than a url.
+func IsLocal(image string) bool {
+	u, err := url.Parse(image)
+	return err != nil || (u.Scheme != "http" && u.Scheme != "https")
+}
+
+// DefaultColor is the colour of nodes without one.
+var DefaultColor = color.RGBA{0x3a, 0x3d, 0x45, 0xff}
+
+// Fill returns the colour of the node, DefaultColor when it has none.
+func (n Node) Fill() color.RGBA {
+	if c, ok := ParseHexColor(n.Color); ok {
+		return c
+	}
+	return DefaultColor
+}
+
+// SortNodes sorts nodes by score, best first. Equal scores are ordered by
+// type and ID, then by image and label, so the same nodes always end up in
+// the same order.
+func SortNodes(nodes []Node) {
+	slices.SortStableFunc(nodes, func(i, j Node) int {
+		return cmp.Or(
+			cmp.Compare(j.Score, i.Score),
+			cmp.Compare(i.Type, j.Type),
+			cmp.Compare(i.ID, j.ID),
+			strings.Compare(i.Image, j.Image),
+			strings.Compare(i.Label, j.Label),
+		)
+	})
+}
+
+// ParseHexColor parses colours in the #rrggbb or #rgb form AniList uses.
+func ParseHexColor(s string) (color.RGBA, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return color.RGBA{}, false
+	}
+
+	var v [3]uint8
+	for i := range 3 {
+		hi, ok1 := hexDigit(s[i*2])
+		lo, ok2 := hexDigit(s[i*2+1])
+		if !ok1 || !ok2 {
+			return color.RGBA{}, false
+		}
+		v[i] = hi<<4 | lo
+	}
+
+	return color.RGBA{v[0], v[1], v[2], 0xff}, true
+}
+
+func hexDigit(c byte) (uint8, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
@@ -0,0 +1,36 @@
+package hexgrid
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected color.RGBA
+		ok       bool
+	}{
+		{"#e4a15d", color.RGBA{0xe4, 0xa1, 0x5d, 0xff}, true},
+		{"#FFF", color.RGBA{0xff, 0xff, 0xff, 0xff}, true},
+		{"", color.RGBA{}, false},
+		{"#12345z", color.RGBA{}, false},
+	}
+
+	for _, tt := range tests {
+		c, ok := ParseHexColor(tt.input)
+		if c != tt.expected || ok != tt.ok {
+			t.Errorf("ParseHexColor(%q) = %v, %v; want %v, %v", tt.input, c, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestNodeFill(t *testing.T) {
+	if c := (Node{Color: "#ff0000"}).Fill(); c != (color.RGBA{0xff, 0, 0, 0xff}) {
+		t.Errorf("Expected node colour, got %v", c)
+	}
+
+	if c := (Node{}).Fill(); c != DefaultColor {
+		t.Errorf("Expected neutral colour without a node colour, got %v", c)
+	}
+}
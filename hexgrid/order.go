@@ -0,0 +1,157 @@
+package hexgrid
+
+import (
+	"image/color"
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+// Order decides which nodes end up next to each other.
+type Order string
+
+const (
+	// OrderScore puts higher scores closer to the centre.
+	OrderScore Order = "score"
+	// OrderScoreTopLeft puts higher scores closer to the top-left corner.
+	OrderScoreTopLeft Order = "score-tl"
+	// OrderRandom shuffles the nodes, see Shuffle.
+	OrderRandom Order = "random"
+	// OrderColor puts hues around the centre and lightness by ring, light
+	// covers inside and dark ones outside.
+	// The wedges of LayoutSectors are split by type, not by hue, so
+	// the two can't be combined.
+	OrderColor Order = "color"
+)
+
+// Arrange orders nodes so node i goes in hexagon i. Nodes must be sorted by
+// score, or shuffled for OrderRandom, and hexagons by their distance from
+// the centre or the top-left corner for OrderScoreTopLeft.
+func (o Order) Arrange(layout Layout, hexs []Hexagon, nodes []Node) []Node {
+	if o != OrderColor {
+		return layout.Arrange(hexs, nodes)
+	}
+
+	// Only the best nodes fit the grid, colours decide where they go
+	kept := min(len(nodes), len(hexs))
+	rest := nodes[kept:]
+	nodes = slices.Clone(nodes[:kept])
+
+	return append(arrangeColors(hexs, nodes), rest...)
+}
+
+// Shuffle shuffles nodes in place with the seed, the user stays first.
+func Shuffle(nodes []Node, seed uint64) {
+	if i := slices.IndexFunc(nodes, func(n Node) bool { return n.Type == UserNode }); i >= 0 {
+		nodes[0], nodes[i] = nodes[i], nodes[0]
+		nodes = nodes[1:]
+	}
+
+	r := rand.New(rand.NewPCG(seed, seed))
+	r.Shuffle(len(nodes), func(i, j int) { nodes[i], nodes[j] = nodes[j], nodes[i] })
+}
+
+// arrangeColors keeps the user in the centre, fills the rings from light to
+// dark and sorts each ring by hue going clockwise from the top.
+func arrangeColors(hexs []Hexagon, nodes []Node) []Node {
+	if len(nodes) == 0 {
+		return nodes
+	}
+
+	centre := max(slices.IndexFunc(nodes, func(n Node) bool { return n.Type == UserNode }), 0)
+	others := slices.Delete(slices.Clone(nodes), centre, centre+1)
+	slices.SortStableFunc(others, func(i, j Node) int {
+		_, _, li := nodeHSL(i)
+		_, _, lj := nodeHSL(j)
+		return compareFloat(lj, li)
+	})
+
+	arranged := make([]Node, len(nodes))
+	arranged[0] = nodes[centre]
+
+	origin := hexs[0].Center
+	for _, ring := range Rings(hexs)[1:] {
+		n := min(len(ring), len(others))
+		batch := others[:n]
+		others = others[n:]
+		slices.SortStableFunc(batch, compareHue)
+
+		ring = slices.Clone(ring)
+		slices.SortStableFunc(ring, func(i, j int) int {
+			return compareFloat(clockwise(origin, hexs[i].Center), clockwise(origin, hexs[j].Center))
+		})
+
+		for k, node := range batch {
+			arranged[ring[k]] = node
+		}
+	}
+
+	return arranged
+}
+
+// clockwise returns the angle of p around origin, clockwise from the top.
+func clockwise(origin, p Point) float64 {
+	a := math.Atan2(p.X-origin.X, origin.Y-p.Y)
+	if a < 0 {
+		a += 2 * math.Pi
+	}
+	return a
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareHue orders nodes by hue. Greys have no hue and come first.
+func compareHue(i, j Node) int {
+	hi, si, _ := nodeHSL(i)
+	hj, sj, _ := nodeHSL(j)
+	if si < 0.1 {
+		hi = -1
+	}
+	if sj < 0.1 {
+		hj = -1
+	}
+	return compareFloat(hi, hj)
+}
+
+// nodeHSL returns the hue in degrees, saturation and lightness of the colour
+// of the node.
+func nodeHSL(node Node) (float64, float64, float64) {
+	return HSL(node.Fill())
+}
+
+// HSL converts c to hue in degrees, saturation and lightness.
+func HSL(c color.RGBA) (float64, float64, float64) {
+	r, g, b := float64(c.R)/0xff, float64(c.G)/0xff, float64(c.B)/0xff
+	hi, lo := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	l := (hi + lo) / 2
+	if hi == lo {
+		return 0, 0, l
+	}
+
+	d := hi - lo
+	s := d / (1 - math.Abs(2*l-1))
+
+	var h float64
+	switch hi {
+	case r:
+		h = math.Mod((g-b)/d, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+
+	return h, s, l
+}
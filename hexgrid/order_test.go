@@ -0,0 +1,112 @@
+package hexgrid
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestHSL(t *testing.T) {
+	tests := []struct {
+		c       color.RGBA
+		h, s, l float64
+	}{
+		{color.RGBA{0xff, 0, 0, 0xff}, 0, 1, 0.5},
+		{color.RGBA{0, 0xff, 0, 0xff}, 120, 1, 0.5},
+		{color.RGBA{0, 0, 0xff, 0xff}, 240, 1, 0.5},
+		{color.RGBA{0xff, 0xff, 0xff, 0xff}, 0, 0, 1},
+	}
+
+	for _, tt := range tests {
+		h, s, l := HSL(tt.c)
+		if math.Abs(h-tt.h) > 1e-9 || math.Abs(s-tt.s) > 1e-9 || math.Abs(l-tt.l) > 1e-9 {
+			t.Errorf("HSL(%v) = %f, %f, %f; want %f, %f, %f", tt.c, h, s, l, tt.h, tt.s, tt.l)
+		}
+	}
+}
+
+func TestOrderColor(t *testing.T) {
+	hexs := GenerateHexagonRing(19, 300, 300, 30)
+
+	// Colours of every hue, from dark to light
+	nodes := []Node{{Type: UserNode}}
+	for i := range len(hexs) {
+		h := float64(i*37%360) / 360
+		l := 0.2 + 0.6*float64(i%5)/5
+		r, g, b := hueToRGB(h, l)
+		nodes = append(nodes, Node{Type: AnimeNode, Color: fmt.Sprintf("#%02x%02x%02x", r, g, b)})
+	}
+
+	arranged := OrderColor.Arrange(LayoutRings, hexs, nodes)
+	if arranged[0].Type != UserNode {
+		t.Errorf("Expected the user in the centre")
+	}
+	if len(arranged) != len(nodes) {
+		t.Errorf("Expected %d nodes, got %d", len(nodes), len(arranged))
+	}
+
+	origin := hexs[0].Center
+	for _, ring := range Rings(hexs)[1:] {
+		type placed struct{ angle, hue float64 }
+		var ps []placed
+		for _, i := range ring {
+			h, _, _ := nodeHSL(arranged[i])
+			ps = append(ps, placed{clockwise(origin, hexs[i].Center), h})
+		}
+		for _, a := range ps {
+			for _, b := range ps {
+				if a.angle < b.angle && a.hue > b.hue {
+					t.Errorf("Expected hue to grow clockwise, got %.0f° at %.2f and %.0f° at %.2f", a.hue, a.angle, b.hue, b.angle)
+				}
+			}
+		}
+	}
+
+	// Lighter covers are in the inner ring
+	rings := Rings(hexs)
+	lightness := func(ring []int) float64 {
+		sum := 0.0
+		for _, i := range ring {
+			_, _, l := nodeHSL(arranged[i])
+			sum += l
+		}
+		return sum / float64(len(ring))
+	}
+	if lightness(rings[1]) <= lightness(rings[2]) {
+		t.Errorf("Expected the inner ring to be lighter")
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	nodes := layoutNodes(map[NodeType]int{AnimeNode: 20})
+
+	a, b := slices.Clone(nodes), slices.Clone(nodes)
+	Shuffle(a, 42)
+	Shuffle(b, 42)
+	if !slices.Equal(a, b) {
+		t.Errorf("Expected the same seed to give the same order")
+	}
+	if slices.Equal(a, nodes) {
+		t.Errorf("Expected the nodes to be shuffled")
+	}
+	if a[0].Type != UserNode {
+		t.Errorf("Expected the user to stay first, got %v", a[0].Type)
+	}
+
+	Shuffle(b, 43)
+	if slices.Equal(a, b) {
+		t.Errorf("Expected another seed to give another order")
+	}
+}
+
+// hueToRGB returns a saturated colour with hue h in [0, 1) and lightness l.
+func hueToRGB(h, l float64) (uint8, uint8, uint8) {
+	f := func(n float64) uint8 {
+		k := math.Mod(n+h*12, 12)
+		a := 0.8 * math.Min(l, 1-l)
+		return uint8(255 * (l - a*math.Max(-1, math.Min(k-3, math.Min(9-k, 1)))))
+	}
+	return f(0), f(8), f(4)
+}
@@ -1,15 +1,18 @@
-package main
+package hexgrid
 
 import "math"
 
+// Point is a position on the image.
 type Point struct {
 	X, Y float64
 }
 
+// Value returns the coordinates of the point.
 func (p Point) Value() (float64, float64) {
 	return p.X, p.Y
 }
 
+// NewPoint returns the point at x, y.
 func NewPoint(x, y float64) Point {
 	return Point{x, y}
 }
@@ -28,6 +31,7 @@ func (p *Point) Rotate(base Point, angle float64) {
 	p.Y = math.Round(newY)
 }
 
+// Distance returns the distance between p and d.
 func (p Point) Distance(d Point) float64 {
 	return math.Sqrt(math.Pow(p.X-d.X, 2) + math.Pow(p.Y-d.Y, 2))
 }
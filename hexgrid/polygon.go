@@ -0,0 +1,92 @@
+package hexgrid
+
+import (
+	"image"
+	"math"
+	"slices"
+)
+
+// Polygon is a closed outline. Hexagons of the grid share edges but never
+// overlap, and every pixel is assigned to at most one of them, so polygons of
+// the grid can be painted into the same image concurrently.
+type Polygon []Point
+
+// Bounds returns the pixel rectangle covering the polygon.
+func (poly Polygon) Bounds() image.Rectangle {
+	if len(poly) == 0 {
+		return image.Rectangle{}
+	}
+
+	minX, minY := poly[0].X, poly[0].Y
+	maxX, maxY := minX, minY
+	for _, p := range poly[1:] {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+
+	return image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+}
+
+// Translate returns the polygon moved by (dx, dy).
+func (poly Polygon) Translate(dx, dy float64) Polygon {
+	moved := make(Polygon, len(poly))
+	for i, p := range poly {
+		moved[i] = Point{p.X + dx, p.Y + dy}
+	}
+	return moved
+}
+
+// Crossings appends the sorted x positions where the horizontal line at y
+// crosses the outline. Edges are half-open in y and always walked from their
+// upper end, so two polygons sharing an edge agree on every crossing.
+func (poly Polygon) Crossings(y float64, xs []float64) []float64 {
+	xs = xs[:0]
+	for i := range poly {
+		a, b := poly[i], poly[(i+1)%len(poly)]
+		if a.Y > b.Y || (a.Y == b.Y && a.X > b.X) {
+			a, b = b, a
+		}
+		if y < a.Y || y >= b.Y {
+			continue
+		}
+		xs = append(xs, a.X+(y-a.Y)*(b.X-a.X)/(b.Y-a.Y))
+	}
+	slices.Sort(xs)
+	return xs
+}
+
+// Spans calls fn for each run of pixels in row y whose centre lies inside the
+// polygon, clipped to clip. Runs are half-open: [x0, x1).
+func (poly Polygon) Spans(y int, clip image.Rectangle, xs []float64, fn func(x0, x1 int)) []float64 {
+	xs = poly.Crossings(float64(y)+0.5, xs)
+	for i := 0; i+1 < len(xs); i += 2 {
+		x0 := max(int(math.Ceil(xs[i]-0.5)), clip.Min.X)
+		x1 := min(int(math.Ceil(xs[i+1]-0.5)), clip.Max.X)
+		if x0 < x1 {
+			fn(x0, x1)
+		}
+	}
+	return xs
+}
+
+// Contains reports whether the pixel centre of (x, y) lies inside the polygon.
+func (poly Polygon) Contains(x, y int) bool {
+	inside := false
+	poly.Spans(y, image.Rect(x, y, x+1, y+1), nil, func(int, int) { inside = true })
+	return inside
+}
+
+// Below returns the part of the polygon below the horizontal line at y.
+func (poly Polygon) Below(y float64) Polygon {
+	var out Polygon
+	for i := range poly {
+		a, b := poly[i], poly[(i+1)%len(poly)]
+		if a.Y >= y {
+			out = append(out, a)
+		}
+		if (a.Y < y) != (b.Y < y) {
+			out = append(out, Point{a.X + (y-a.Y)*(b.X-a.X)/(b.Y-a.Y), y})
+		}
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package hexgrid
+
+import (
+	"image"
+	"testing"
+)
+
+func TestPolygonsDontOverlap(t *testing.T) {
+	hexs := GenerateHexagonRing(19, 200, 200, 30)
+	bounds := image.Rect(0, 0, 400, 400)
+
+	owners := make(map[image.Point]int)
+	for i, hex := range hexs {
+		poly := hex.Outline()
+		var xs []float64
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			xs = poly.Spans(y, bounds, xs, func(x0, x1 int) {
+				for x := x0; x < x1; x++ {
+					p := image.Pt(x, y)
+					if j, ok := owners[p]; ok {
+						t.Fatalf("Pixel %v belongs to hexagon %d and %d", p, j, i)
+					}
+					owners[p] = i
+				}
+			})
+		}
+	}
+
+	// Pixels on the shared edge of the first two hexagons belong to one of them
+	a, b := hexs[0], hexs[1]
+	mid := image.Pt(int((a.Center.X+b.Center.X)/2), int((a.Center.Y+b.Center.Y)/2))
+	if _, ok := owners[mid]; !ok {
+		t.Errorf("Expected pixel %v between neighbours to be painted", mid)
+	}
+}
+
+func TestPolygonContains(t *testing.T) {
+	poly := NewHexagon(50, 50, 20, 0).Outline()
+
+	if !poly.Contains(50, 50) {
+		t.Errorf("Expected center to be inside")
+	}
+	if poly.Contains(31, 31) {
+		t.Errorf("Expected corner of the bounding box to be outside")
+	}
+}
+
+func TestPolygonBelow(t *testing.T) {
+	hex := NewHexagon(50, 50, 20, 0)
+	banner := hex.Outline().Below(60)
+
+	bounds := banner.Bounds()
+	if bounds.Min.Y != 60 || bounds.Max.Y != hex.Box().Rect().Max.Y {
+		t.Errorf("Expected banner from 60 to the bottom, got %v", bounds)
+	}
+	if banner.Contains(50, 55) || !banner.Contains(50, 65) {
+		t.Errorf("Expected only the part below 60 in the banner")
+	}
+}
@@ -0,0 +1,220 @@
+package hexgrid
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fogleman/gg"
+	_ "golang.org/x/image/webp"
+)
+
+// Mask is a silhouette the hexagons of a grid are laid out in.
+type Mask interface {
+	// Contains reports whether a hexagon centred at (x, y) may be filled.
+	Contains(x, y float64) bool
+}
+
+// MaskFunc adapts a function to a Mask.
+type MaskFunc func(x, y float64) bool
+
+// Contains calls f(x, y).
+func (f MaskFunc) Contains(x, y float64) bool { return f(x, y) }
+
+// Anchor is where a shaped grid starts filling from.
+type Anchor string
+
+const (
+	AnchorCenter Anchor = "center"
+	AnchorTop    Anchor = "top"
+	AnchorBottom Anchor = "bottom"
+	AnchorLeft   Anchor = "left"
+	AnchorRight  Anchor = "right"
+)
+
+// Point returns the position of the anchor in area.
+func (a Anchor) Point(area image.Rectangle) (Point, error) {
+	cx, cy := float64(area.Min.X+area.Max.X)/2, float64(area.Min.Y+area.Max.Y)/2
+	switch a {
+	case AnchorCenter, "":
+		return Point{cx, cy}, nil
+	case AnchorTop:
+		return Point{cx, float64(area.Min.Y)}, nil
+	case AnchorBottom:
+		return Point{cx, float64(area.Max.Y)}, nil
+	case AnchorLeft:
+		return Point{float64(area.Min.X), cy}, nil
+	case AnchorRight:
+		return Point{float64(area.Max.X), cy}, nil
+	}
+	return Point{}, fmt.Errorf("unknown anchor %q, expected center, top, bottom, left or right", a)
+}
+
+// CellsInside returns the hexagons of the cell whose centres lie inside the
+// mask and area. The hexagons are aligned on a lattice through the centre of
+// the area and come in no particular order.
+func CellsInside(mask Mask, area image.Rectangle, cell Cell) []Hexagon {
+	origin := cell.Hexagon(float64(area.Min.X+area.Max.X)/2, float64(area.Min.Y+area.Max.Y)/2)
+	grid := NewHexGrid(origin)
+	bounds := image.Rectangle{area.Min.Sub(image.Pt(1, 1)), area.Max.Add(image.Pt(1, 1))}
+
+	// Walk the whole lattice inside the area, holes of the mask included
+	seen := map[string]bool{grid.Key(origin.Center.X, origin.Center.Y): true}
+	queue := []Point{origin.Center}
+	var hexs []Hexagon
+	for len(queue) != 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		hex := cell.Hexagon(p.X, p.Y)
+		if mask.Contains(p.X, p.Y) {
+			hexs = append(hexs, hex)
+		}
+
+		for _, n := range hex.Neiboors() {
+			key := grid.Key(n.X, n.Y)
+			if seen[key] || !image.Pt(int(n.X), int(n.Y)).In(bounds) {
+				continue
+			}
+			seen[key] = true
+			queue = append(queue, n)
+		}
+	}
+
+	return hexs
+}
+
+// GenerateShape generates up to n hexagons of the cell inside the mask,
+// sorted by their distance from the anchor.
+func GenerateShape(n int, mask Mask, area image.Rectangle, anchor Point, cell Cell) []Hexagon {
+	hexs := CellsInside(mask, area, cell)
+	SortHexagons(hexs, anchor)
+	return hexs[:min(n, len(hexs))]
+}
+
+// GenerateRect tiles area with hexagons of the cell edge to edge, sorted by
+// their distance from the anchor. Hexagons crossing the border of area are
+// included, drawing them on a canvas of the same size clips them.
+func GenerateRect(area image.Rectangle, anchor Point, cell Cell) []Hexagon {
+	r := int(math.Ceil(cell.Radius))
+	hexs := CellsInside(MaskFunc(func(x, y float64) bool { return true }), area.Inset(-r), cell)
+	hexs = slices.DeleteFunc(hexs, func(hex Hexagon) bool {
+		return !hex.Outline().Bounds().Overlaps(area)
+	})
+	SortHexagons(hexs, anchor)
+	return hexs
+}
+
+// SortHexagons sorts hexagons by their distance from the anchor, going
+// clockwise from the top between hexagons at the same distance.
+func SortHexagons(hexs []Hexagon, anchor Point) {
+	slices.SortStableFunc(hexs, func(i, j Hexagon) int { return compareAround(anchor, i.Center, j.Center) })
+}
+
+// compareAround orders points by their distance from the anchor, then
+// clockwise from the top. Distances closer than rounding errors are equal,
+// so points of a ring always come in the same order.
+func compareAround(anchor, p, q Point) int {
+	if d := p.Distance(anchor) - q.Distance(anchor); math.Abs(d) > 1e-6 {
+		return compareFloat(d, 0)
+	}
+	return compareFloat(clockwise(anchor, p), clockwise(anchor, q))
+}
+
+// ParseShape returns the mask of a named shape fitted in area, or of a mask
+// image or SVG file.
+func ParseShape(shape string, area image.Rectangle, cell Cell) (Mask, error) {
+	w, h := float64(area.Dx()), float64(area.Dy())
+	cx, cy := float64(area.Min.X)+w/2, float64(area.Min.Y)+h/2
+
+	switch shape {
+	case "rectangle":
+		return MaskFunc(func(x, y float64) bool { return true }), nil
+	case "hexagon":
+		// Rings of hexagons grow into a hexagon turned by 30°
+		outline := NewHexagon(cx, cy, math.Min(w, h)/2, cell.Orientation.Angle()+math.Pi/6).Outline()
+		return PolygonMask{outline}, nil
+	case "heart":
+		return heartMask(cx, cy, math.Min(w, h)/2), nil
+	}
+
+	switch strings.ToLower(filepath.Ext(shape)) {
+	case ".svg":
+		return LoadSVGMask(shape, area)
+	case ".png", ".jpg", ".jpeg", ".gif", ".webp":
+		return LoadImageMask(shape, area)
+	}
+
+	if _, err := os.Stat(shape); err == nil {
+		return LoadImageMask(shape, area)
+	}
+	return nil, fmt.Errorf("unknown shape %q, expected hexagon, rectangle, heart or a PNG or SVG mask", shape)
+}
+
+// heartMask returns a heart of the given radius centred at (cx, cy).
+func heartMask(cx, cy, radius float64) MaskFunc {
+	return func(x, y float64) bool {
+		// The heart curve spans about [-1.14, 1.14] × [-1, 1.25]
+		u := (x - cx) / radius * 1.2
+		v := -(y-cy)/radius*1.2 + 0.1
+		a := u*u + v*v - 1
+		return a*a*a-u*u*v*v*v <= 0
+	}
+}
+
+// PolygonMask fills the inside of the polygons with the even-odd rule.
+type PolygonMask []Polygon
+
+// Contains reports whether x, y is inside the polygon.
+func (m PolygonMask) Contains(x, y float64) bool {
+	inside := false
+	var xs []float64
+	for _, poly := range m {
+		xs = poly.Crossings(y, xs)
+		for _, cx := range xs {
+			if cx < x {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// ImageMask fills the dark, opaque pixels of an image stretched over an
+// area.
+type ImageMask struct {
+	Image image.Image
+	Area  image.Rectangle
+}
+
+// LoadImageMask loads a black and white mask image for area.
+func LoadImageMask(path string, area image.Rectangle) (ImageMask, error) {
+	img, err := gg.LoadImage(path)
+	if err != nil {
+		return ImageMask{}, fmt.Errorf("failed to load mask: %w", err)
+	}
+	return ImageMask{Image: img, Area: area}, nil
+}
+
+// Contains reports whether the image is opaque at x, y.
+func (m ImageMask) Contains(x, y float64) bool {
+	b := m.Image.Bounds()
+	px := b.Min.X + int((x-float64(m.Area.Min.X))/float64(m.Area.Dx())*float64(b.Dx()))
+	py := b.Min.Y + int((y-float64(m.Area.Min.Y))/float64(m.Area.Dy())*float64(b.Dy()))
+	if !image.Pt(px, py).In(b) {
+		return false
+	}
+
+	r, g, bl, a := m.Image.At(px, py).RGBA()
+	if a < 0x8000 {
+		return false
+	}
+	return 0.2126*float64(r)+0.7152*float64(g)+0.0722*float64(bl) < 0x8000
+}
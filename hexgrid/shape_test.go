@@ -0,0 +1,213 @@
+package hexgrid
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCellsInside(t *testing.T) {
+	area := image.Rect(0, 0, 400, 400)
+	cell := Cell{Radius: 20, Orientation: Flat}
+
+	all := CellsInside(MaskFunc(func(x, y float64) bool { return true }), area, cell)
+	left := CellsInside(MaskFunc(func(x, y float64) bool { return x < 200 }), area, cell)
+
+	if len(all) < 100 {
+		t.Fatalf("Expected the area to be covered, got %d cells", len(all))
+	}
+	if len(left) == 0 || len(left) >= len(all) {
+		t.Errorf("Expected the left half to have fewer cells, got %d of %d", len(left), len(all))
+	}
+	for _, hex := range left {
+		if hex.Center.X >= 200 {
+			t.Errorf("Expected cells left of 200, got %v", hex.Center)
+		}
+	}
+
+	// Cells lie on one lattice, no two overlap
+	for i, a := range all {
+		for _, b := range all[i+1:] {
+			if a.Center.Distance(b.Center) < a.Spacing()-1e-6 {
+				t.Fatalf("Expected cells %v and %v to be neighbours at most", a.Center, b.Center)
+			}
+		}
+	}
+}
+
+func TestGenerateShape(t *testing.T) {
+	area := image.Rect(0, 100, 400, 500)
+	cell := Cell{Radius: 20, Orientation: Pointy}
+
+	mask, err := ParseShape("heart", area, cell)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	anchor, err := AnchorBottom.Point(area)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hexs := GenerateShape(20, mask, area, anchor, cell)
+	if len(hexs) != 20 {
+		t.Fatalf("Expected 20 hexagons, got %d", len(hexs))
+	}
+	for i := 1; i < len(hexs); i++ {
+		if hexs[i].Center.Distance(anchor) < hexs[i-1].Center.Distance(anchor)-1e-6 {
+			t.Errorf("Expected hexagons sorted by distance from the anchor")
+		}
+	}
+	if hexs[0].Center.Y < 400 {
+		t.Errorf("Expected the first hexagon at the bottom tip, got %v", hexs[0].Center)
+	}
+
+	if all := GenerateShape(1<<20, mask, area, anchor, cell); len(all) >= 1<<20 {
+		t.Errorf("Expected the shape to limit the hexagons")
+	}
+}
+
+func TestShapes(t *testing.T) {
+	area := image.Rect(0, 0, 200, 200)
+	cell := Cell{Radius: 10}
+
+	tests := []struct {
+		shape           string
+		inside, outside Point
+	}{
+		{"rectangle", Point{2, 2}, Point{-5, -5}},
+		{"hexagon", Point{100, 100}, Point{2, 2}},
+		{"heart", Point{100, 120}, Point{100, 5}},
+	}
+
+	for _, tt := range tests {
+		mask, err := ParseShape(tt.shape, area, cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !mask.Contains(tt.inside.X, tt.inside.Y) {
+			t.Errorf("Expected %s to contain %v", tt.shape, tt.inside)
+		}
+		if tt.shape != "rectangle" && mask.Contains(tt.outside.X, tt.outside.Y) {
+			t.Errorf("Expected %s to leave out %v", tt.shape, tt.outside)
+		}
+	}
+
+	if _, err := ParseShape("star", area, cell); err == nil {
+		t.Errorf("Expected an error for an unknown shape")
+	}
+}
+
+func TestImageMask(t *testing.T) {
+	// Black left half, white right half
+	img := image.NewGray(image.Rect(0, 0, 10, 10))
+	for y := range 10 {
+		for x := range 10 {
+			if x >= 5 {
+				img.SetGray(x, y, color.Gray{0xff})
+			}
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "mask.png")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	mask, err := ParseShape(path, image.Rect(100, 100, 300, 300), Cell{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mask.Contains(150, 200) || mask.Contains(250, 200) || mask.Contains(50, 200) {
+		t.Errorf("Expected only the black half inside the mask")
+	}
+}
+
+func TestSVGMask(t *testing.T) {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 20 10">
+		<g><path d="M0 0H10V10H0Z M2 2v6h6v-6z"/></g>
+	</svg>`
+
+	path := filepath.Join(t.TempDir(), "mask.svg")
+	if err := os.WriteFile(path, []byte(svg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The 20x10 view box is scaled to 200x100 and centred vertically
+	mask, err := ParseShape(path, image.Rect(0, 0, 200, 200), Cell{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !mask.Contains(10, 60) {
+		t.Errorf("Expected the frame of the square inside")
+	}
+	if mask.Contains(50, 100) {
+		t.Errorf("Expected the hole of the square outside")
+	}
+	if mask.Contains(150, 100) || mask.Contains(10, 10) {
+		t.Errorf("Expected outside the path to be outside")
+	}
+}
+
+func TestParseSVGPath(t *testing.T) {
+	polys, err := ParseSVGPath("m10-5l5,5 -5.5.5zM0 0C0 10 10 10 10 0Q5-5 0 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(polys) != 2 {
+		t.Fatalf("Expected 2 polygons, got %d", len(polys))
+	}
+	if polys[0][1] != (Point{15, 0}) || polys[0][2] != (Point{9.5, 0.5}) {
+		t.Errorf("Expected relative points, got %v", polys[0])
+	}
+	if len(polys[1]) != 1+2*svgCurveSteps {
+		t.Errorf("Expected flattened curves, got %d points", len(polys[1]))
+	}
+
+	if _, err := ParseSVGPath("M0 0 L5"); err == nil {
+		t.Errorf("Expected an error for a truncated path")
+	}
+}
+
+func TestGenerateRect(t *testing.T) {
+	area := image.Rect(0, 50, 300, 150)
+
+	for _, orient := range []Orientation{Flat, Pointy} {
+		cell := Cell{Radius: 20, Orientation: orient}
+		hexs := GenerateRect(area, Point{0, 50}, cell)
+
+		// Every pixel of the area is covered, border included
+		for y := area.Min.Y; y < area.Max.Y; y += 5 {
+			for x := area.Min.X; x < area.Max.X; x += 5 {
+				covered := false
+				for _, hex := range hexs {
+					if hex.Outline().Contains(x, y) {
+						covered = true
+						break
+					}
+				}
+				if !covered {
+					t.Fatalf("Expected %s hexagons to cover (%d, %d)", orient, x, y)
+				}
+			}
+		}
+
+		for _, hex := range hexs {
+			if !hex.Outline().Bounds().Overlaps(area) {
+				t.Errorf("Expected %s hexagon at %v to overlap the area", orient, hex.Center)
+			}
+		}
+		if c := hexs[0].Center; c.X > 20 || c.Y > 70 {
+			t.Errorf("Expected the first %s hexagon at the top-left corner, got %v", orient, c)
+		}
+	}
+}
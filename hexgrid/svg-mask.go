@@ -0,0 +1,333 @@
+package hexgrid
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// svgFile holds the parts of an SVG file a mask is made of.
+type svgFile struct {
+	ViewBox string `xml:"viewBox,attr"`
+	Width   string `xml:"width,attr"`
+	Height  string `xml:"height,attr"`
+	Paths   []struct {
+		D string `xml:"d,attr"`
+	} `xml:"path"`
+	Groups []svgFile `xml:"g"`
+}
+
+// paths returns the path data of the file, groups included.
+func (f svgFile) paths() []string {
+	var paths []string
+	for _, p := range f.Paths {
+		paths = append(paths, p.D)
+	}
+	for _, g := range f.Groups {
+		paths = append(paths, g.paths()...)
+	}
+	return paths
+}
+
+// LoadSVGMask loads the paths of an SVG file as a mask fitted in area. The
+// view box, or the size of the file, is centred in area keeping its aspect
+// ratio. Transforms and styles are ignored.
+func LoadSVGMask(path string, area image.Rectangle) (PolygonMask, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mask: %w", err)
+	}
+
+	var file svgFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse mask: %w", err)
+	}
+
+	var mask PolygonMask
+	for _, d := range file.paths() {
+		polys, err := ParseSVGPath(d)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse mask: %w", err)
+		}
+		mask = append(mask, polys...)
+	}
+	if len(mask) == 0 {
+		return nil, fmt.Errorf("mask %s has no paths", path)
+	}
+
+	view, ok := file.viewBox()
+	if !ok {
+		view = mask.bounds()
+	}
+
+	scale := math.Min(float64(area.Dx())/view.W, float64(area.Dy())/view.H)
+	dx := float64(area.Min.X) + (float64(area.Dx())-view.W*scale)/2 - view.X*scale
+	dy := float64(area.Min.Y) + (float64(area.Dy())-view.H*scale)/2 - view.Y*scale
+	for _, poly := range mask {
+		for i, p := range poly {
+			poly[i] = Point{p.X*scale + dx, p.Y*scale + dy}
+		}
+	}
+
+	return mask, nil
+}
+
+// viewBox returns the view box of the file, falling back to its size.
+func (f svgFile) viewBox() (Box, bool) {
+	if fields := strings.FieldsFunc(f.ViewBox, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }); len(fields) == 4 {
+		var v [4]float64
+		for i, field := range fields {
+			n, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return Box{}, false
+			}
+			v[i] = n
+		}
+		if v[2] > 0 && v[3] > 0 {
+			return Box{v[0], v[1], v[2], v[3]}, true
+		}
+	}
+
+	w, errW := strconv.ParseFloat(strings.TrimSuffix(f.Width, "px"), 64)
+	h, errH := strconv.ParseFloat(strings.TrimSuffix(f.Height, "px"), 64)
+	if errW != nil || errH != nil || w <= 0 || h <= 0 {
+		return Box{}, false
+	}
+	return Box{0, 0, w, h}, true
+}
+
+// bounds returns the box around every polygon of the mask.
+func (m PolygonMask) bounds() Box {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, poly := range m {
+		for _, p := range poly {
+			minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+			minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+		}
+	}
+	return NewBox(Point{minX, minY}, Point{maxX, maxY})
+}
+
+// svgCurveSteps is the number of segments curves are flattened into.
+const svgCurveSteps = 16
+
+// ParseSVGPath flattens SVG path data into polygons, one per subpath.
+// Arcs are replaced by straight lines to their end point.
+func ParseSVGPath(d string) ([]Polygon, error) {
+	tokens := tokenizeSVGPath(d)
+
+	var polys []Polygon
+	var poly Polygon
+	var cur, start, ctrl Point
+	var prev byte
+
+	closePoly := func() {
+		if len(poly) > 2 {
+			polys = append(polys, poly)
+		}
+		poly = nil
+	}
+
+	i := 0
+	next := func(n int) ([]float64, error) {
+		if i+n > len(tokens) {
+			return nil, fmt.Errorf("path ends in the middle of a command")
+		}
+		v := make([]float64, n)
+		for k := range n {
+			f, err := strconv.ParseFloat(tokens[i+k], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q in path", tokens[i+k])
+			}
+			v[k] = f
+		}
+		i += n
+		return v, nil
+	}
+
+	var cmd byte
+	for i < len(tokens) {
+		if t := tokens[i]; len(t) == 1 && unicode.IsLetter(rune(t[0])) {
+			cmd = t[0]
+			i++
+		} else if cmd == 0 {
+			return nil, fmt.Errorf("path starts with %q instead of a command", t)
+		}
+
+		rel := unicode.IsLower(rune(cmd))
+		abs := func(x, y float64) Point {
+			if rel {
+				return Point{cur.X + x, cur.Y + y}
+			}
+			return Point{x, y}
+		}
+
+		switch unicode.ToUpper(rune(cmd)) {
+		case 'M':
+			v, err := next(2)
+			if err != nil {
+				return nil, err
+			}
+			closePoly()
+			cur = abs(v[0], v[1])
+			start = cur
+			poly = Polygon{cur}
+			// Further pairs are line segments
+			if rel {
+				cmd = 'l'
+			} else {
+				cmd = 'L'
+			}
+		case 'L':
+			v, err := next(2)
+			if err != nil {
+				return nil, err
+			}
+			cur = abs(v[0], v[1])
+			poly = append(poly, cur)
+		case 'H':
+			v, err := next(1)
+			if err != nil {
+				return nil, err
+			}
+			if rel {
+				cur.X += v[0]
+			} else {
+				cur.X = v[0]
+			}
+			poly = append(poly, cur)
+		case 'V':
+			v, err := next(1)
+			if err != nil {
+				return nil, err
+			}
+			if rel {
+				cur.Y += v[0]
+			} else {
+				cur.Y = v[0]
+			}
+			poly = append(poly, cur)
+		case 'C', 'S':
+			var c1 Point
+			var v []float64
+			var err error
+			if unicode.ToUpper(rune(cmd)) == 'C' {
+				if v, err = next(6); err != nil {
+					return nil, err
+				}
+				c1 = abs(v[0], v[1])
+				v = v[2:]
+			} else {
+				if v, err = next(4); err != nil {
+					return nil, err
+				}
+				c1 = cur
+				if p := unicode.ToUpper(rune(prev)); p == 'C' || p == 'S' {
+					c1 = Point{2*cur.X - ctrl.X, 2*cur.Y - ctrl.Y}
+				}
+			}
+			c2, end := abs(v[0], v[1]), abs(v[2], v[3])
+			for s := 1; s <= svgCurveSteps; s++ {
+				t := float64(s) / svgCurveSteps
+				u := 1 - t
+				poly = append(poly, Point{
+					u*u*u*cur.X + 3*u*u*t*c1.X + 3*u*t*t*c2.X + t*t*t*end.X,
+					u*u*u*cur.Y + 3*u*u*t*c1.Y + 3*u*t*t*c2.Y + t*t*t*end.Y,
+				})
+			}
+			ctrl, cur = c2, end
+		case 'Q', 'T':
+			var c Point
+			var v []float64
+			var err error
+			if unicode.ToUpper(rune(cmd)) == 'Q' {
+				if v, err = next(4); err != nil {
+					return nil, err
+				}
+				c = abs(v[0], v[1])
+				v = v[2:]
+			} else {
+				if v, err = next(2); err != nil {
+					return nil, err
+				}
+				c = cur
+				if p := unicode.ToUpper(rune(prev)); p == 'Q' || p == 'T' {
+					c = Point{2*cur.X - ctrl.X, 2*cur.Y - ctrl.Y}
+				}
+			}
+			end := abs(v[0], v[1])
+			for s := 1; s <= svgCurveSteps; s++ {
+				t := float64(s) / svgCurveSteps
+				u := 1 - t
+				poly = append(poly, Point{
+					u*u*cur.X + 2*u*t*c.X + t*t*end.X,
+					u*u*cur.Y + 2*u*t*c.Y + t*t*end.Y,
+				})
+			}
+			ctrl, cur = c, end
+		case 'A':
+			v, err := next(7)
+			if err != nil {
+				return nil, err
+			}
+			cur = abs(v[5], v[6])
+			poly = append(poly, cur)
+		case 'Z':
+			closePoly()
+			cur = start
+			poly = Polygon{cur}
+		default:
+			return nil, fmt.Errorf("unknown path command %q", cmd)
+		}
+		prev = cmd
+	}
+	closePoly()
+
+	return polys, nil
+}
+
+// tokenizeSVGPath splits path data into commands and numbers.
+func tokenizeSVGPath(d string) []string {
+	var tokens []string
+	var num strings.Builder
+
+	flush := func() {
+		if num.Len() != 0 {
+			tokens = append(tokens, num.String())
+			num.Reset()
+		}
+	}
+
+	for _, r := range d {
+		switch {
+		case unicode.IsLetter(r) && r != 'e' && r != 'E':
+			flush()
+			tokens = append(tokens, string(r))
+		case r == '-' || r == '+':
+			// A sign starts a new number unless it belongs to an exponent
+			if s := num.String(); s != "" && !strings.HasSuffix(s, "e") && !strings.HasSuffix(s, "E") {
+				flush()
+			}
+			num.WriteRune(r)
+		case r == '.':
+			// A second dot starts a new number, as in "0.5.5"
+			if strings.Contains(num.String(), ".") {
+				flush()
+			}
+			num.WriteRune(r)
+		case unicode.IsDigit(r) || r == 'e' || r == 'E':
+			num.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return tokens
+}
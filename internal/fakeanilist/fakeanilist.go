@@ -0,0 +1,227 @@
+// Package fakeanilist is a stand-in for the AniList GraphQL API, its OAuth2
+// endpoints and its image CDN, serving a fixture profile for offline tests.
+package fakeanilist
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+)
+
+const (
+	// CDN is the image host of the fixture covers. Rewrite it to Server.URL
+	// to fetch them from the server.
+	CDN = "https://s4.anilist.co"
+
+	// Code is the authorization code the server accepts.
+	Code = "fake-code"
+	// Token is the access token the server hands out and expects in Viewer
+	// queries.
+	Token = "fake-access-token"
+	// RefreshToken is the refresh token the server hands out and accepts.
+	RefreshToken = "fake-refresh-token"
+
+	// EmptyUser is the name of a user without lists or favourites.
+	EmptyUser = "Empty"
+)
+
+//go:embed fixtures
+var fixtures embed.FS
+
+// Server serves the fixture profile. Queries are counted by name: Viewer,
+// User, MediaListCollection, token and image.
+type Server struct {
+	*httptest.Server
+
+	user  json.RawMessage
+	id    int64
+	name  string
+	lists map[string]json.RawMessage
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+// New starts a server. Close it when done.
+func New() *Server {
+	s := &Server{lists: make(map[string]json.RawMessage), requests: make(map[string]int)}
+
+	s.user = mustRead("fixtures/user.json")
+	var user struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(s.user, &user); err != nil {
+		panic(err)
+	}
+	s.id, s.name = user.ID, user.Name
+	s.lists["ANIME"] = mustRead("fixtures/anime.json")
+	s.lists["MANGA"] = mustRead("fixtures/manga.json")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /graphql", s.graphql)
+	mux.HandleFunc("GET /oauth/authorize", s.authorize)
+	mux.HandleFunc("POST /oauth/token", s.token)
+	mux.HandleFunc("GET /file/anilistcdn/", s.image)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func mustRead(name string) json.RawMessage {
+	b, err := fixtures.ReadFile(name)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// GraphQL returns the url of the GraphQL endpoint.
+func (s *Server) GraphQL() string { return s.URL + "/graphql" }
+
+// AuthURL returns the url of the OAuth2 authorization endpoint.
+func (s *Server) AuthURL() string { return s.URL + "/oauth/authorize" }
+
+// TokenURL returns the url of the OAuth2 token endpoint.
+func (s *Server) TokenURL() string { return s.URL + "/oauth/token" }
+
+// UserID returns the ID of the fixture user.
+func (s *Server) UserID() int64 { return s.id }
+
+// UserName returns the name of the fixture user.
+func (s *Server) UserName() string { return s.name }
+
+// Requests returns how many requests of the kind the server answered.
+func (s *Server) Requests(kind string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[kind]
+}
+
+func (s *Server) count(kind string) {
+	s.mu.Lock()
+	s.requests[kind]++
+	s.mu.Unlock()
+}
+
+func (s *Server) graphql(w http.ResponseWriter, r *http.Request) {
+	var query struct {
+		Query     string         `json:"query"`
+		Variables map[string]any `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON body.")
+		return
+	}
+
+	// Only the root field of the query matters, the fixtures hold every
+	// field the client asks for
+	switch {
+	case strings.Contains(query.Query, "MediaListCollection("):
+		s.count("MediaListCollection")
+		userID, _ := query.Variables["userId"].(float64)
+		kind, _ := query.Variables["type"].(string)
+		list, ok := s.lists[kind]
+		if !ok || int64(userID) != s.id {
+			writeJSON(w, http.StatusOK, []byte(`{"data":{"MediaListCollection":{"lists":[]}}}`))
+			return
+		}
+		writeJSON(w, http.StatusOK, list)
+
+	case strings.Contains(query.Query, "Viewer"):
+		s.count("Viewer")
+		if r.Header.Get("Authorization") != "Bearer "+Token {
+			writeError(w, http.StatusUnauthorized, "Invalid token")
+			return
+		}
+		writeJSON(w, http.StatusOK, fmt.Appendf(nil, `{"data":{"Viewer":%s}}`, s.user))
+
+	case strings.Contains(query.Query, "User("):
+		s.count("User")
+		name, _ := query.Variables["name"].(string)
+		if strings.EqualFold(name, EmptyUser) {
+			writeJSON(w, http.StatusOK, fmt.Appendf(nil, `{"data":{"User":{"id":%d,"name":%q}}}`, s.id+1, EmptyUser))
+			return
+		}
+		if !strings.EqualFold(name, s.name) {
+			writeError(w, http.StatusNotFound, "Not Found.")
+			return
+		}
+		writeJSON(w, http.StatusOK, fmt.Appendf(nil, `{"data":{"User":%s}}`, s.user))
+
+	default:
+		writeError(w, http.StatusBadRequest, "Unsupported query.")
+	}
+}
+
+// authorize grants access right away, redirecting back with Code.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	redirect := r.URL.Query().Get("redirect_uri")
+	if redirect == "" {
+		http.Error(w, "missing redirect_uri", http.StatusBadRequest)
+		return
+	}
+	http.Redirect(w, r, redirect+"?code="+Code, http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	s.count("token")
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case r.Form.Get("grant_type") == "authorization_code" && r.Form.Get("code") == Code:
+	case r.Form.Get("grant_type") == "refresh_token" && r.Form.Get("refresh_token") == RefreshToken:
+	default:
+		writeJSON(w, http.StatusBadRequest, []byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, fmt.Appendf(nil,
+		`{"access_token":%q,"token_type":"Bearer","expires_in":3600,"refresh_token":%q}`, Token, RefreshToken))
+}
+
+// image serves the fixture cover with the name of the requested file,
+// whatever directory it is requested from.
+func (s *Server) image(w http.ResponseWriter, r *http.Request) {
+	s.count("image")
+	b, err := fs.ReadFile(fixtures, "fixtures/covers/"+path.Base(r.URL.Path))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	sum := sha256.Sum256(b)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// writeError answers with a GraphQL error the way AniList does.
+func writeError(w http.ResponseWriter, status int, message string) {
+	body, _ := json.Marshal(map[string]any{
+		"data":   nil,
+		"errors": []map[string]any{{"message": message, "status": status}},
+	})
+	writeJSON(w, status, body)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Nadim147c/hexanilist/anilist"
+)
+
+const (
+	ansiGreen = "\033[32m" // ANSI escape code for green
+	ansiBlue  = "\033[34m" // ANSI escape code for blue
+	ansiReset = "\033[0m"  // Reset color
+)
+
+// login returns a client logged in to AniList. The credentials of the API
+// client and the authorization code are asked for on stdin when none are
+// saved.
+func login(ctx context.Context) (*anilist.Client, error) {
+	cred, err := anilist.LoadCredentials()
+	if err != nil || cred.ID == "" || cred.Secret == "" {
+		cred, err = promptCredentials()
+		if err != nil {
+			return nil, err
+		}
+		if err := anilist.SaveCredentials(cred); err != nil {
+			slog.Error("Failed to save credentials", "error", err)
+		}
+	}
+
+	client, err := anilist.New(ctx, Endpoints, cred)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.Login()
+	if !errors.Is(err, anilist.ErrLoginRequired) {
+		return client, err
+	}
+	slog.Warn("Failed to load access-token from disk", "reason", err)
+
+	fmt.Printf("%sOpen the following URL in your browser and authorize the application:%s\n", ansiGreen, ansiReset)
+	fmt.Printf(" - %s%s%s\n\n", ansiBlue, client.LoginURL(), ansiReset)
+
+	var code string
+	fmt.Print("Paste the code: ")
+	fmt.Scanln(&code)
+
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, errors.New("no authorization code given")
+	}
+
+	if err := client.Exchange(code); err != nil {
+		return nil, err
+	}
+	if err := client.SaveToken(); err != nil {
+		slog.Error("Failed to save access-token", "error", err)
+	}
+	return client, nil
+}
+
+// promptCredentials asks for the id and secret of an AniList API client.
+func promptCredentials() (anilist.Credentials, error) {
+	var id, secret string
+
+	fmt.Printf("%sYou need create an Anilist app!%s\n", ansiGreen, ansiReset)
+	fmt.Printf(" - Goto %s%s%s\n", ansiBlue, "https://anilist.co/settings/developer", ansiReset)
+	fmt.Println(" - Create New Client")
+	fmt.Printf(" - Give it name and set Redirect URL to %s%s%s\n\n", ansiBlue, Endpoints.RedirectURL, ansiReset)
+
+	fmt.Print("Enter Client ID: ")
+	fmt.Scanln(&id)
+	fmt.Print("Enter Client Secret: ")
+	fmt.Scanln(&secret)
+
+	cred := anilist.Credentials{ID: strings.TrimSpace(id), Secret: strings.TrimSpace(secret)}
+	if cred.ID == "" || cred.Secret == "" {
+		return cred, anilist.ErrNoCredentials
+	}
+	return cred, nil
+}
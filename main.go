@@ -2,42 +2,88 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"image"
-	"image/color"
+	"image/png"
+	"io"
 	"log/slog"
+	"net/http"
 	"os"
-	"runtime"
-	"slices"
+	"os/signal"
+	"path/filepath"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
 
-	"github.com/disintegration/imaging"
-	"github.com/fogleman/gg"
+	"github.com/Nadim147c/hexanilist/anilist"
+	"github.com/Nadim147c/hexanilist/hexgrid"
+	"github.com/Nadim147c/hexanilist/render"
+	"github.com/Nadim147c/hexanilist/server"
+	"github.com/Nadim147c/hexanilist/source"
 	"github.com/spf13/pflag"
+	"golang.org/x/oauth2"
 )
 
-type NodeType uint64
-
-const (
-	UserNode NodeType = iota
-	AnimeNode
-	MangaNode
-	CharacterNode
-)
-
-type HexagonNode struct {
-	Type  NodeType
-	Image Image
-	Score int
-}
-
 var (
-	CellSize = 50
-	Size     = 2000
-	Username = ""
-	Output   = "hexagon.png"
+	CellSize   = 50
+	Size       = 2000
+	Username   = ""
+	Output     = "hexagon.png"
+	CellOrient = string(hexgrid.Flat)
+	CellGap    = 0.0
+	CellCorner = 0.0
+	GridLayout = string(hexgrid.LayoutRings)
+	GridOrder  = string(hexgrid.OrderScore)
+	GridShape  = ""
+	GridAnchor = string(hexgrid.AnchorCenter)
+	GridWidth  = 0
+	GridHeight = 0
+	Seed       = uint64(0)
+	MultiScale = false
+
+	StrokeWidth     = float64(render.DefaultStrokeWidth)
+	StrokeAlignment = string(render.StrokeCenter)
+	DashPlanning    = true
+	Shadow          = false
+
+	MalExports     []string
+	MalMappingFile = ""
+
+	FromDir = ""
+	Scores  = ""
+	FromCSV = ""
+
+	CacheDir     = ""
+	CacheTTL     = render.DefaultCacheTTL
+	CacheMaxSize = int64(render.DefaultCacheMaxSize >> 20)
+
+	Client    = anilist.DefaultHTTPConfig()
+	Endpoints = anilist.DefaultEndpoints
+
+	Placeholder = string(render.PlaceholderNone)
+
+	Downloads    = render.DefaultDownloads
+	ShowProgress = true
+
+	Labels = false
+	Badges = false
+
+	ShowHeader = false
+	ShowFooter = false
+
+	Animate       = ""
+	FrameDelay    = 100 * time.Millisecond
+	FramesPerRing = 1
+
+	Watch    = time.Duration(0)
+	OnRender = ""
+
+	Listen         = ":8080"
+	ServeTTL       = server.DefaultTTL
+	ServeCacheSize = int64(server.DefaultCacheSize >> 20)
+	MaxRenders     = server.DefaultMaxRenders
+	MaxQueue       = server.DefaultMaxQueue
 )
 
 func init() {
@@ -45,9 +91,56 @@ func init() {
 	pflag.IntVarP(&Size, "size", "s", Size, "Size of main image")
 	pflag.StringVarP(&Username, "user", "u", Username, "Username of Anilist")
 	pflag.StringVarP(&Output, "out", "o", Output, "Output file name")
+	pflag.StringVar(&CellOrient, "orientation", CellOrient, "Orientation of the hexagons: flat or pointy")
+	pflag.Float64Var(&CellGap, "gap", CellGap, "Space between neighbouring hexagons in pixels")
+	pflag.StringVar(&GridLayout, "layout", GridLayout, "Placement of the nodes: rings, sectors or rect")
+	pflag.IntVar(&GridWidth, "width", GridWidth, "Width of the rect layout (default --size)")
+	pflag.IntVar(&GridHeight, "height", GridHeight, "Height of the rect layout (default --size)")
+	pflag.StringVar(&GridOrder, "order", GridOrder, "Order of the nodes: score, score-tl, random or color")
+	pflag.Uint64Var(&Seed, "seed", Seed, "Seed of the random order (0 for a new one each run)")
+	pflag.BoolVar(&MultiScale, "multi-scale", MultiScale, "Draw the avatar 3 cells wide and favourites and top scores 2 cells wide")
+	pflag.StringVar(&GridShape, "shape", GridShape, "Shape of the grid: hexagon, rectangle, heart, or a PNG or SVG mask")
+	pflag.StringVar(&GridAnchor, "anchor", GridAnchor, "Where shaped grids start filling: center, top, bottom, left or right")
+	pflag.Float64Var(&CellCorner, "corner-radius", CellCorner, "Radius of the rounded corners of the hexagons")
+	pflag.Float64Var(&StrokeWidth, "stroke-width", StrokeWidth, "Width of the outline of the hexagons (0 for none)")
+	pflag.StringVar(&StrokeAlignment, "stroke-align", StrokeAlignment, "Position of the outline: center, inner or outer")
+	pflag.BoolVar(&DashPlanning, "dash-planning", DashPlanning, "Dash the outline of planned entries")
+	pflag.BoolVar(&Shadow, "shadow", Shadow, "Drop a shadow below the hexagons")
+	pflag.StringSliceVar(&MalExports, "mal-export", MalExports, "MyAnimeList export (.xml or .xml.gz) to use instead of Anilist lists")
+	pflag.StringVar(&MalMappingFile, "mal-mapping", MalMappingFile, "JSON file mapping MyAnimeList IDs to Anilist covers")
+	pflag.StringVar(&FromDir, "from-dir", FromDir, "Directory of images to use instead of Anilist")
+	pflag.StringVar(&Scores, "scores", Scores, "JSON or CSV file with scores for --from-dir images")
+	pflag.StringVar(&FromCSV, "from-csv", FromCSV, "CSV file with image,score,label,link columns to use instead of Anilist")
+	pflag.StringVar(&CacheDir, "cache-dir", CacheDir, "Directory of the image cache (default in the user cache directory)")
+	pflag.DurationVar(&CacheTTL, "cache-ttl", CacheTTL, "Time before cached images are revalidated (0 to never)")
+	pflag.Int64Var(&CacheMaxSize, "cache-max-size", CacheMaxSize, "Size limit of the image cache in MiB (0 for no limit)")
+	pflag.DurationVar(&Client.Timeout, "timeout", Client.Timeout, "Timeout of each HTTP request")
+	pflag.StringVar(&Client.UserAgent, "user-agent", Client.UserAgent, "User-Agent of HTTP requests")
+	pflag.StringVar(&Client.Proxy, "proxy", Client.Proxy, "Proxy url for HTTP requests (default from environment)")
+	pflag.IntVar(&Client.Retries, "retries", Client.Retries, "Retries for failed HTTP requests")
+	pflag.IntVar(&Downloads, "downloads", Downloads, "Number of concurrent image downloads")
+	pflag.BoolVar(&ShowProgress, "progress", ShowProgress, "Show render progress")
+	pflag.StringVar(&Placeholder, "placeholder-text", Placeholder, "Text on hexagons without an image: none, initials or title")
+	pflag.BoolVar(&Labels, "labels", Labels, "Draw titles across the lower third of the hexagons")
+	pflag.BoolVar(&Badges, "badges", Badges, "Draw score badges and status glyphs on the hexagons")
+	pflag.BoolVar(&ShowHeader, "header", ShowHeader, "Draw a header with the avatar, name and stats of the user above the grid")
+	pflag.BoolVar(&ShowFooter, "footer", ShowFooter, "Draw a footer with the generation date below the grid")
+	pflag.StringVar(&Animate, "animate", Animate, "Write an animation revealing the grid ring by ring: gif or apng")
+	pflag.DurationVar(&FrameDelay, "frame-delay", FrameDelay, "Delay between frames of the animation")
+	pflag.IntVar(&FramesPerRing, "frames-per-ring", FramesPerRing, "Frames each ring of the animation is split into")
+	pflag.DurationVar(&Watch, "watch", Watch, "Poll the source on this interval and render again when it changes (0 to render once)")
+	pflag.StringVar(&OnRender, "on-render", OnRender, "Shell command run after each render, the output path is in $HEXANILIST_OUTPUT")
+	pflag.StringVar(&Listen, "listen", Listen, "Address the serve command listens on")
+	pflag.DurationVar(&ServeTTL, "serve-ttl", ServeTTL, "Time the serve command reuses a rendered grid")
+	pflag.Int64Var(&ServeCacheSize, "serve-cache-size", ServeCacheSize, "Size limit of the grids the serve command keeps in MiB")
+	pflag.IntVar(&MaxRenders, "max-renders", MaxRenders, "Grids the serve command renders at once")
+	pflag.IntVar(&MaxQueue, "max-queue", MaxQueue, "Renders the serve command lets wait for a slot")
+	pflag.StringToStringVar(&Client.Rewrites, "mirror", Client.Rewrites, "Rewrite url prefixes, e.g. https://s4.anilist.co=http://localhost:8080")
 
 	pflag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s cache prune [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s serve [--listen addr] [options]\n", os.Args[0])
 		fmt.Fprint(os.Stderr, "Generate Hexagon grid from anilist media\n\n")
 		fmt.Fprintln(os.Stderr, "Options:")
 		fmt.Fprintln(os.Stderr, pflag.CommandLine.FlagUsages())
@@ -56,214 +149,405 @@ func init() {
 	pflag.Parse()
 }
 
-func main() {
-	anilist := NewAnilist(context.Background())
-	defer anilist.SaveToken()
+// usageError is an error in the flags, reported with exit code 2.
+type usageError struct{ error }
+
+func usagef(format string, args ...any) error {
+	return usageError{fmt.Errorf(format, args...)}
+}
 
-	if err := anilist.Login(); err != nil {
-		panic(err)
+// exitCode returns the exit code reporting err: 2 for invalid flags, 130 for
+// an interrupt and 1 for anything else.
+func exitCode(err error) int {
+	var usage usageError
+	switch {
+	case errors.As(err, &usage):
+		return 2
+	case errors.Is(err, context.Canceled):
+		return 130
+	default:
+		return 1
 	}
+}
 
-	var user User
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if Username != "" {
-		slog.Info("Fetching user data", "username", Username)
-		u, err := anilist.GetUser(Username)
-		if err != nil {
-			panic(err)
-		}
-		user = u.Data.User
-	} else {
-		u, err := anilist.GetCurrentUser()
-		if err != nil {
-			panic(err)
-		}
-		user = u.Data.User
+	if err := run(ctx, pflag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitCode(err))
 	}
+}
 
-	fmt.Println("User ID:", user.ID)
-	fmt.Println("User Name:", user.Name)
+// run generates the grid described by the flags, or runs the subcommand given
+// by args.
+func run(ctx context.Context, args []string) error {
+	client, err := anilist.NewHTTPClient(Client)
+	if err != nil {
+		return usageError{err}
+	}
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
 
-	anime, manga, err := anilist.GetList(user.ID)
+	cache, err := render.DefaultImageCache()
+	if CacheDir != "" {
+		cache, err = render.NewImageCache(CacheDir), nil
+	}
 	if err != nil {
-		panic(err)
+		return err
 	}
+	cache.Client = client
+	cache.TTL = CacheTTL
+	cache.MaxSize = CacheMaxSize << 20
+	ctx = render.WithImageCache(ctx, cache)
 
-	start := time.Now()
+	if len(args) != 0 && args[0] == "cache" {
+		return runCacheCommand(cache, args[1:])
+	}
+
+	grid := gridFromFlags()
+	if err := grid.Validate(); err != nil {
+		return usageError{err}
+	}
+	if Animate != "" {
+		if err := render.AnimationFormat(Animate).Validate(); err != nil {
+			return usageError{err}
+		}
+	}
 
-	nodes := buildNodes(user, anime, manga)
-	slices.SortFunc(nodes, func(i, j HexagonNode) int { return j.Score - i.Score })
+	if len(args) != 0 && args[0] == "serve" {
+		return runServeCommand(ctx, cache, grid)
+	}
 
-	hexs := GenerateHexagonRing(len(nodes), float64(Size/2), float64(Size/2), float64(CellSize))
-	ctx := gg.NewContext(Size, Size)
-	ctx.SetLineWidth(5)
-	ctx.SetStrokeStyle(gg.NewSolidPattern(color.Black))
+	// The progress line is redrawn in place, which only works on a terminal
+	if ShowProgress && isTerminal(os.Stderr) {
+		grid.Renderer.Progress = os.Stderr
+	}
 
-	renderHexagons(ctx, hexs, nodes)
+	if grid.Order == hexgrid.OrderRandom && grid.Seed == 0 {
+		grid.Seed = uint64(time.Now().UnixNano())
+		slog.Info("Shuffling nodes", "seed", grid.Seed)
+	}
 
-	if !strings.HasSuffix(Output, ".png") {
-		Output += ".png"
+	// Logging in may ask for a code, it is done once, not on every poll
+	api, err := anilistClient(ctx)
+	if err != nil {
+		return err
 	}
 
-	ctx.SavePNG(Output)
-	slog.Info("Saving output", "output", Output, "took", time.Since(start))
+	if Watch > 0 {
+		return watch(ctx, cache, api, grid, Watch)
+	}
+
+	_, err = update(ctx, cache, api, grid, "")
+	return err
+}
+
+// isTerminal reports whether f is a terminal rather than a file or a pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
-func buildNodes(user User, anime AnimeList, manga MangaList) []HexagonNode {
-	userNode := HexagonNode{
-		Type:  UserNode,
-		Score: 1 << 60,
-		Image: user.Avatar.Medium,
+// anilistClient logs in to AniList when the nodes come from the lists of the
+// user. Returns nil for the other sources.
+func anilistClient(ctx context.Context) (*anilist.Client, error) {
+	if FromDir != "" || FromCSV != "" || len(MalExports) != 0 {
+		return nil, nil
 	}
+	return login(ctx)
+}
 
-	nodes := []HexagonNode{userNode}
-	nodes = append(nodes, buildCharacterNodes(user)...)
+// update loads the nodes and draws the grid into the output, unless the hash
+// of the nodes is last. Returns the hash of the nodes.
+func update(ctx context.Context, cache *render.ImageCache, client *anilist.Client, grid render.Grid, last string) (string, error) {
+	nodeSource, err := loadSource(ctx, client)
+	if err != nil {
+		return last, err
+	}
 
-	nodeChan := make(chan HexagonNode)
-	var wg sync.WaitGroup
+	nodes, err := nodeSource.Nodes()
+	if err != nil {
+		return last, err
+	}
 
-	wg.Add(2)
-	go processAnimeList(anime, user, nodeChan, &wg)
-	go processMangaList(manga, user, nodeChan, &wg)
+	hash := source.Hash(nodes, grid.Order)
+	if hash == last {
+		slog.Info("Nodes unchanged, skipping render", "nodes", len(nodes))
+		return hash, nil
+	}
 
-	go func() {
-		wg.Wait()
-		close(nodeChan)
-	}()
+	start := time.Now()
 
-	for node := range nodeChan {
-		nodes = append(nodes, node)
+	if grid.Header != nil {
+		*grid.Header = headerFor(nodeSource, nodes)
+	}
+	if !grid.Footer.IsZero() {
+		grid.Footer = start
 	}
 
-	return nodes
-}
+	img, err := grid.Draw(ctx, nodes)
 
-func buildCharacterNodes(user User) []HexagonNode {
-	var nodes []HexagonNode
-	for _, char := range user.Favourites.Characters.Nodes {
-		characterNode := HexagonNode{
-			Type:  CharacterNode,
-			Score: 500,
-			Image: char.Image.Medium,
-		}
-		nodes = append(nodes, characterNode)
+	if cerr := cache.Save(); cerr != nil {
+		slog.Error("Failed to save image cache index", "error", cerr)
 	}
-	return nodes
-}
 
-func processAnimeList(anime AnimeList, user User, nodeChan chan<- HexagonNode, wg *sync.WaitGroup) {
-	defer wg.Done()
+	if err != nil {
+		return last, fmt.Errorf("render interrupted: %w", err)
+	}
 
-	for _, list := range anime.Lists {
-		for _, entry := range list.Entries {
-			score := calculateScore(entry.Score, entry.Status, user.Favourites.Anime.Has(entry.ID))
+	output, err := saveOutput(grid, img)
+	if err != nil {
+		return last, err
+	}
 
-			animeNode := HexagonNode{
-				Type:  AnimeNode,
-				Score: score,
-				Image: entry.Cover.Medium,
-			}
+	slog.Info("Saving output", "output", output, "took", time.Since(start))
+	if img.Stats.Placeholders != 0 {
+		slog.Warn("Some hexagons have no image", "placeholders", img.Stats.Placeholders, "hexagons", img.Stats.Hexagons)
+	}
 
-			nodeChan <- animeNode
+	if OnRender != "" {
+		if err := runHook(ctx, OnRender, output); err != nil {
+			return hash, err
 		}
 	}
+	return hash, nil
 }
 
-func processMangaList(manga MangaList, user User, nodeChan chan<- HexagonNode, wg *sync.WaitGroup) {
-	defer wg.Done()
-
-	for _, list := range manga.Lists {
-		for _, entry := range list.Entries {
-			score := calculateScore(entry.Score, entry.Status, user.Favourites.Manga.Has(entry.ID))
+// loadSource returns the source of the nodes chosen by the flags. client is
+// the one of anilistClient.
+func loadSource(ctx context.Context, client *anilist.Client) (source.Source, error) {
+	switch {
+	case FromDir != "":
+		return source.Dir{Dir: FromDir, Sidecar: Scores}, nil
+	case FromCSV != "":
+		return source.CSV{Path: FromCSV}, nil
+	default:
+		return loadAnilistSource(ctx, client)
+	}
+}
 
-			mangaNode := HexagonNode{
-				Type:  MangaNode, // Fixed: was AnimeNode, should be MangaNode
-				Score: score,
-				Image: entry.Cover.Medium,
-			}
+// saveOutput writes the grid, or its animation, to Output. Returns the path
+// it was saved to.
+func saveOutput(grid render.Grid, img render.GridImage) (string, error) {
+	if Animate != "" {
+		anim := grid.Animation(img, render.AnimationFormat(Animate), FrameDelay, FramesPerRing)
+		return saveAnimation(Output, anim, img.Image, img.Hexagons)
+	}
 
-			nodeChan <- mangaNode
-		}
+	output := Output
+	if !strings.HasSuffix(output, ".png") {
+		output += ".png"
+	}
+	err := writeFile(output, func(w io.Writer) error { return png.Encode(w, img.Image) })
+	if err != nil {
+		return output, fmt.Errorf("failed to save output: %w", err)
 	}
+	return output, nil
 }
 
-func calculateScore(userScore *float64, status Status, isFavorite bool) int {
-	var score int = 0
+// headerFor creates the header of the grid. Sources other than AniList have
+// no user, their header shows the image count instead.
+func headerFor(nodeSource source.Source, nodes []hexgrid.Node) render.Header {
+	if src, ok := nodeSource.(source.Anilist); ok {
+		return render.NewHeader(src.User, src.Anime, src.Manga)
+	}
 
-	if userScore != nil {
-		score = int(*userScore * 10)
+	name := Username
+	if name == "" {
+		name = filepath.Base(FromDir + FromCSV)
 	}
+	return render.Header{Name: name, Stats: []string{fmt.Sprintf("%d images", len(nodes))}}
+}
 
-	switch status {
-	case Completed:
-		score += 100
-	case Dropped:
-		score -= 100
+// saveAnimation encodes the animation of the grid into output, replacing its
+// extension with the one of the format. Returns the path it was saved to.
+func saveAnimation(output string, anim render.Animation, final *image.RGBA, hexs []hexgrid.Hexagon) (string, error) {
+	ext := ".png"
+	if anim.Format == render.AnimateGIF {
+		ext = ".gif"
 	}
+	output = strings.TrimSuffix(output, filepath.Ext(output)) + ext
 
-	if isFavorite {
-		score += 200
+	err := writeFile(output, func(w io.Writer) error { return anim.Encode(w, final, hexs) })
+	if err != nil {
+		return output, fmt.Errorf("failed to encode animation: %w", err)
 	}
+	return output, nil
+}
 
-	return score
+// writeFile writes the file at path through a temporary file in the same
+// directory, so readers never see a partly written image.
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
 }
 
-func renderHexagons(ctx *gg.Context, hexs []Hexagon, nodes []HexagonNode) {
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	maxConcurrent := runtime.NumCPU()
-	sem := make(chan struct{}, maxConcurrent)
+// runServeCommand serves the grids of AniList users on Listen until ctx is
+// cancelled. The flags are the defaults of the grids.
+func runServeCommand(ctx context.Context, cache *render.ImageCache, grid render.Grid) error {
+	handler := server.New(ctx, grid, Endpoints)
+	handler.TTL = ServeTTL
+	handler.CacheSize = ServeCacheSize << 20
+	handler.MaxRenders = MaxRenders
+	handler.MaxQueue = MaxQueue
 
-	for i, hex := range hexs {
-		wg.Add(1)
-		sem <- struct{}{} // acquire
+	srv := &http.Server{Addr: Listen, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
 
-		go func(i int, hex Hexagon) {
-			defer wg.Done()
-			defer func() { <-sem }() // release
+	slog.Info("Serving grids", "address", Listen, "example", "/u/<name>.png?cell=60")
+	err := srv.ListenAndServe()
 
-			if err := renderSingleHexagon(ctx, hex, nodes[i], &mu); err != nil {
-				slog.Error("Failed to render hexagon", "index", i, "error", err)
-			}
-		}(i, hex)
+	if cerr := cache.Save(); cerr != nil {
+		slog.Error("Failed to save image cache index", "error", cerr)
 	}
 
-	wg.Wait()
+	if errors.Is(err, http.ErrServerClosed) {
+		return ctx.Err()
+	}
+	return err
 }
 
-func renderSingleHexagon(ctx *gg.Context, hex Hexagon, node HexagonNode, mu *sync.Mutex) error {
-	path, err := node.Image.Download()
-	if err != nil {
-		return fmt.Errorf("failed to download image: %w", err)
+// runCacheCommand runs the cache subcommand given by args.
+func runCacheCommand(cache *render.ImageCache, args []string) error {
+	if len(args) == 0 || args[0] != "prune" {
+		return fmt.Errorf("usage: %s cache prune [--cache-ttl duration] [--cache-max-size MiB]", os.Args[0])
 	}
 
-	img, err := gg.LoadImage(path)
+	count, freed, err := cache.Prune()
 	if err != nil {
-		return fmt.Errorf("failed to load image: %w", err)
+		return fmt.Errorf("failed to prune cache: %w", err)
 	}
 
-	w, h := hex.Box().Size()
-	cropped := imaging.Fill(img, w, h, imaging.Center, imaging.Lanczos)
-
-	mu.Lock()
-	defer mu.Unlock()
+	if err := cache.Save(); err != nil {
+		return fmt.Errorf("failed to save cache index: %w", err)
+	}
 
-	drawHexagonWithImage(ctx, hex, cropped)
+	fmt.Printf("Removed %d files, freed %.1f MiB\n", count, float64(freed)/(1<<20))
 	return nil
 }
 
-func drawHexagonWithImage(ctx *gg.Context, hex Hexagon, img image.Image) {
-	// Clear path and set clipping
-	ctx.ClearPath()
-	hex.Draw(ctx)
-	ctx.Clip()
+// loadAnilistSource fetches the user and their lists from AniList with the
+// logged in client, or reads the lists from MyAnimeList exports when they are
+// given.
+func loadAnilistSource(ctx context.Context, client *anilist.Client) (source.Anilist, error) {
+	var src source.Anilist
+
+	if len(MalExports) != 0 {
+		public := anilist.NewPublic(ctx, Endpoints)
+
+		info, anime, manga, err := anilist.LoadMalLists(public, MalExports, MalMappingFile)
+		if err != nil {
+			return src, err
+		}
+		src.Anime, src.Manga = anime, manga
+		src.User = anilist.User{ID: info.UserID, Name: info.UserName}
+
+		if Username != "" {
+			slog.Info("Fetching user data", "username", Username)
+			u, err := public.GetUser(Username)
+			if err != nil {
+				return src, err
+			}
+			src.User = u.Data.User
+		}
 
-	// Draw the image
-	x, y := hex.Box().Start()
-	ctx.DrawImage(img, x, y)
-	ctx.ResetClip()
+		slog.Info("Loaded MAL export", "user", src.User.Name)
+		return src, nil
+	}
+
+	if Username != "" {
+		slog.Info("Fetching user data", "username", Username)
+		u, err := client.GetUser(Username)
+		if err != nil {
+			return src, err
+		}
+		src.User = u.Data.User
+	} else {
+		u, err := client.GetCurrentUser()
+		if err != nil {
+			return src, err
+		}
+		src.User = u.Data.User
+	}
 
-	// Draw stroke around the image
-	hex.Draw(ctx)
-	ctx.Stroke()
+	slog.Info("Fetching lists", "user", src.User.Name, "id", src.User.ID)
+
+	anime, manga, err := client.GetList(src.User.ID)
+	if err != nil {
+		return src, err
+	}
+	src.Anime, src.Manga = anime, manga
+
+	return src, nil
+}
+
+// gridFromFlags returns the grid described by the flags.
+func gridFromFlags() render.Grid {
+	// Only the rect layout may be wider or taller than it is high
+	width, height := Size, Size
+	if hexgrid.Layout(GridLayout) == hexgrid.LayoutRect {
+		if GridWidth > 0 {
+			width = GridWidth
+		}
+		if GridHeight > 0 {
+			height = GridHeight
+		}
+	}
+
+	grid := render.Grid{
+		Cell: hexgrid.Cell{
+			Radius:      float64(CellSize),
+			Orientation: hexgrid.Orientation(CellOrient),
+			Gap:         CellGap,
+			Corner:      CellCorner,
+		},
+		Width:      width,
+		Height:     height,
+		Layout:     hexgrid.Layout(GridLayout),
+		Order:      hexgrid.Order(GridOrder),
+		Seed:       Seed,
+		Shape:      GridShape,
+		Anchor:     hexgrid.Anchor(GridAnchor),
+		MultiScale: MultiScale,
+		Renderer: render.Renderer{
+			Downloads:   Downloads,
+			Placeholder: render.PlaceholderText(Placeholder),
+			Overlay:     render.Overlay{Labels: Labels, Badges: Badges},
+			Stroke: render.Stroke{
+				Width:        StrokeWidth,
+				Align:        render.StrokeAlign(StrokeAlignment),
+				DashPlanning: DashPlanning,
+				Shadow:       Shadow,
+			},
+		},
+	}
+	if ShowHeader {
+		// The header is filled in once the nodes are loaded
+		grid.Header = &render.Header{}
+	}
+	if ShowFooter {
+		grid.Footer = time.Now()
+	}
+	return grid
 }
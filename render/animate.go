@@ -0,0 +1,339 @@
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"math"
+	"time"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+)
+
+// AnimationFormat is the container of an animated grid.
+type AnimationFormat string
+
+const (
+	AnimateGIF  AnimationFormat = "gif"
+	AnimateAPNG AnimationFormat = "apng"
+)
+
+// Validate checks that f is a known format.
+func (f AnimationFormat) Validate() error {
+	switch f {
+	case AnimateGIF, AnimateAPNG:
+		return nil
+	default:
+		return fmt.Errorf("invalid animation format %q, expected gif or apng", f)
+	}
+}
+
+// DefaultAnimationHold is how long the finished grid is shown before the
+// animation loops.
+const DefaultAnimationHold = 2 * time.Second
+
+// Animation reveals a rendered grid ring by ring, starting at the centre.
+// Every frame only holds the pixels revealed since the frame before, so the
+// size of a frame is bounded by its hexagons rather than by the whole grid.
+type Animation struct {
+	Format        AnimationFormat
+	Delay         time.Duration // Time each frame is shown.
+	FramesPerRing int           // Frames each ring is split into.
+	Hold          time.Duration // Time the finished grid is shown before looping.
+	Margin        float64       // How far strokes and shadows reach outside the hexagons.
+
+	Static []image.Rectangle // Areas outside the grid shown from the first frame.
+}
+
+// frames splits the rings of the grid into frames of hexagon indices.
+func (a Animation) frames(hexs []hexgrid.Hexagon) [][]int {
+	per := max(a.FramesPerRing, 1)
+
+	var frames [][]int
+	for i, ring := range hexgrid.Rings(hexs) {
+		n := per
+		if i == 0 {
+			n = 1
+		}
+		n = min(n, len(ring))
+
+		for f := range n {
+			start, end := f*len(ring)/n, (f+1)*len(ring)/n
+			frames = append(frames, ring[start:end])
+		}
+	}
+
+	return frames
+}
+
+// frameDelay returns the delay of frame i out of n.
+func (a Animation) frameDelay(i, n int) time.Duration {
+	if i == n-1 && a.Hold > a.Delay {
+		return a.Hold
+	}
+	return a.Delay
+}
+
+// reveal calls fn with the pixels each frame adds to the one before. The
+// first frame covers the whole image and shows the static areas, later frames
+// only the area of their hexagons. Pixels that aren't revealed by the frame are transparent.
+func (a Animation) reveal(final *image.RGBA, hexs []hexgrid.Hexagon, fn func(i, n int, frame *image.RGBA) error) error {
+	bounds := final.Bounds()
+	frames := a.frames(hexs)
+	revealed := make([]bool, bounds.Dx()*bounds.Dy())
+
+	for i, indices := range frames {
+		var polys []hexgrid.Polygon
+		var region image.Rectangle
+		for _, idx := range indices {
+			hex := hexs[idx]
+			// Grow the hexagon so its stroke is revealed with it
+			poly := hex.Inset(-(a.Margin + 1)).Outline()
+			polys = append(polys, poly)
+			region = region.Union(poly.Bounds())
+		}
+
+		if i == 0 {
+			region = bounds
+		}
+		region = region.Intersect(bounds)
+		if region.Empty() {
+			// Frames can't be empty, reveal nothing in a single pixel instead
+			region = image.Rectangle{bounds.Min, bounds.Min.Add(image.Pt(1, 1))}
+		}
+
+		frame := image.NewRGBA(region)
+		if i == 0 {
+			for _, rect := range a.Static {
+				rect = rect.Intersect(bounds)
+				for y := rect.Min.Y; y < rect.Max.Y; y++ {
+					for x := rect.Min.X; x < rect.Max.X; x++ {
+						revealed[(y-bounds.Min.Y)*bounds.Dx()+(x-bounds.Min.X)] = true
+						frame.SetRGBA(x, y, final.RGBAAt(x, y))
+					}
+				}
+			}
+		}
+
+		var xs []float64
+		for _, poly := range polys {
+			for y := region.Min.Y; y < region.Max.Y; y++ {
+				xs = poly.Spans(y, region, xs, func(x0, x1 int) {
+					for x := x0; x < x1; x++ {
+						k := (y-bounds.Min.Y)*bounds.Dx() + (x - bounds.Min.X)
+						if revealed[k] {
+							continue
+						}
+						revealed[k] = true
+						frame.SetRGBA(x, y, final.RGBAAt(x, y))
+					}
+				})
+			}
+		}
+
+		if err := fn(i, len(frames), frame); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Encode writes the animation of the rendered grid to w.
+func (a Animation) Encode(w io.Writer, final *image.RGBA, hexs []hexgrid.Hexagon) error {
+	if err := a.Format.Validate(); err != nil {
+		return err
+	}
+	if a.Format == AnimateGIF {
+		return a.encodeGIF(w, final, hexs)
+	}
+	return a.encodeAPNG(w, final, hexs)
+}
+
+// gifPalette is the web safe palette with greys and a transparent colour.
+var gifPalette = func() color.Palette {
+	p := color.Palette{color.Transparent}
+	p = append(p, palette.WebSafe...)
+	for i := range 256 - len(p) {
+		v := uint8(8 + i*6)
+		p = append(p, color.RGBA{v, v, v, 0xff})
+	}
+	return p
+}()
+
+func (a Animation) encodeGIF(w io.Writer, final *image.RGBA, hexs []hexgrid.Hexagon) error {
+	anim := &gif.GIF{Config: image.Config{ColorModel: gifPalette, Width: final.Bounds().Dx(), Height: final.Bounds().Dy()}}
+
+	err := a.reveal(final, hexs, func(i, n int, frame *image.RGBA) error {
+		paletted := image.NewPaletted(frame.Bounds(), gifPalette)
+		draw.FloydSteinberg.Draw(paletted, frame.Bounds(), frame, frame.Bounds().Min)
+
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, int(a.frameDelay(i, n)/(10*time.Millisecond)))
+		anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return gif.EncodeAll(w, anim)
+}
+
+// encodeAPNG streams the frames as an animated PNG. Frames are blended over
+// the previous one, which is never disposed.
+func (a Animation) encodeAPNG(w io.Writer, final *image.RGBA, hexs []hexgrid.Hexagon) error {
+	bw := bufio.NewWriter(w)
+	apng := &apngWriter{w: bw}
+
+	bounds := final.Bounds()
+	apng.header(bounds.Dx(), bounds.Dy(), len(a.frames(hexs)))
+
+	err := a.reveal(final, hexs, func(i, n int, frame *image.RGBA) error {
+		data, err := apngFrameData(frame)
+		if err != nil {
+			return err
+		}
+
+		apng.frame(frame.Bounds().Sub(bounds.Min), a.frameDelay(i, n), data, i == 0)
+		return apng.err
+	})
+	if err != nil {
+		return err
+	}
+
+	apng.chunk("IEND", nil)
+	if apng.err != nil {
+		return apng.err
+	}
+	return bw.Flush()
+}
+
+// apngWriter writes the chunks of an animated PNG.
+type apngWriter struct {
+	w   io.Writer
+	seq uint32
+	err error
+}
+
+func (p *apngWriter) chunk(name string, data []byte) {
+	if p.err != nil {
+		return
+	}
+
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], name)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+
+	var footer [4]byte
+	binary.BigEndian.PutUint32(footer[:], crc.Sum32())
+
+	for _, b := range [][]byte{header[:], data, footer[:]} {
+		if _, err := p.w.Write(b); err != nil {
+			p.err = err
+			return
+		}
+	}
+}
+
+// header writes the signature, the image header and the animation control
+// for frames frames that loop forever.
+func (p *apngWriter) header(width, height, frames int) {
+	if _, err := io.WriteString(p.w, "\x89PNG\r\n\x1a\n"); err != nil {
+		p.err = err
+		return
+	}
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(height))
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 6 // truecolour with alpha
+	p.chunk("IHDR", ihdr)
+
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:], uint32(frames))
+	p.chunk("acTL", actl)
+}
+
+// frame writes a frame covering rect. The first frame is also the default
+// image shown by viewers without APNG support.
+func (p *apngWriter) frame(rect image.Rectangle, delay time.Duration, data []byte, first bool) {
+	const (
+		disposeNone = 0
+		blendOver   = 1
+	)
+
+	fctl := make([]byte, 26)
+	binary.BigEndian.PutUint32(fctl[0:], p.seq)
+	binary.BigEndian.PutUint32(fctl[4:], uint32(rect.Dx()))
+	binary.BigEndian.PutUint32(fctl[8:], uint32(rect.Dy()))
+	binary.BigEndian.PutUint32(fctl[12:], uint32(rect.Min.X))
+	binary.BigEndian.PutUint32(fctl[16:], uint32(rect.Min.Y))
+	binary.BigEndian.PutUint16(fctl[20:], uint16(min(delay.Milliseconds(), math.MaxUint16)))
+	binary.BigEndian.PutUint16(fctl[22:], 1000)
+	fctl[24] = disposeNone
+	fctl[25] = blendOver
+	p.chunk("fcTL", fctl)
+	p.seq++
+
+	if first {
+		p.chunk("IDAT", data)
+		return
+	}
+
+	fdat := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(fdat, p.seq)
+	copy(fdat[4:], data)
+	p.chunk("fdAT", fdat)
+	p.seq++
+}
+
+// apngFrameData compresses the frame as non-premultiplied 8-bit RGBA
+// scanlines, the format declared in the image header.
+func apngFrameData(frame *image.RGBA) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+
+	bounds := frame.Bounds()
+	row := make([]byte, 1+4*bounds.Dx())
+	prev := make([]byte, len(row))
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		line := make([]byte, len(row))
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(frame.RGBAAt(x, y)).(color.NRGBA)
+			i := 1 + 4*(x-bounds.Min.X)
+			line[i], line[i+1], line[i+2], line[i+3] = c.R, c.G, c.B, c.A
+		}
+
+		// Up filter, revealed areas repeat a lot between rows
+		row[0] = 2
+		for i := 1; i < len(row); i++ {
+			row[i] = line[i] - prev[i]
+		}
+		prev = line
+
+		if _, err := zw.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
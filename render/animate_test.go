@@ -0,0 +1,114 @@
+package render
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+)
+
+// animationGrid returns a grid of 19 hexagons filled with one colour each.
+func animationGrid(t *testing.T) (*image.RGBA, []hexgrid.Hexagon) {
+	t.Helper()
+
+	hexs := hexgrid.GenerateHexagonRing(19, 250, 250, 50)
+	final := image.NewRGBA(image.Rect(0, 0, 500, 500))
+	for i, hex := range hexs {
+		FillPolygon(final, hex.Outline(), color.RGBA{uint8(i * 12), 0x80, 0xff, 0xff})
+	}
+	return final, hexs
+}
+
+func TestAnimationFrames(t *testing.T) {
+	_, hexs := animationGrid(t)
+
+	frames := Animation{FramesPerRing: 1}.frames(hexs)
+	if len(frames) != 3 {
+		t.Errorf("Expected a frame per ring, got %d", len(frames))
+	}
+
+	frames = Animation{FramesPerRing: 3}.frames(hexs)
+	if len(frames) != 7 {
+		t.Errorf("Expected 1 + 3 + 3 frames, got %d", len(frames))
+	}
+
+	count := 0
+	for _, frame := range frames {
+		count += len(frame)
+	}
+	if count != len(hexs) {
+		t.Errorf("Expected every hexagon in a frame, got %d of %d", count, len(hexs))
+	}
+}
+
+func TestAnimationGIF(t *testing.T) {
+	final, hexs := animationGrid(t)
+	anim := Animation{Format: AnimateGIF, Delay: 50 * time.Millisecond, FramesPerRing: 1, Hold: time.Second}
+
+	var buf bytes.Buffer
+	if err := anim.Encode(&buf, final, hexs); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.Image) != 3 {
+		t.Fatalf("Expected 3 frames, got %d", len(g.Image))
+	}
+	if g.Delay[0] != 5 || g.Delay[2] != 100 {
+		t.Errorf("Expected delays of 5 and 100, got %v", g.Delay)
+	}
+	if g.Image[1].Bounds() == final.Bounds() {
+		t.Errorf("Expected later frames to only cover their ring")
+	}
+
+	// The centre is drawn by the first frame only
+	center := hexs[0].Center
+	if _, _, _, a := g.Image[1].At(int(center.X), int(center.Y)).RGBA(); a != 0 {
+		t.Errorf("Expected the second frame to leave the centre untouched")
+	}
+}
+
+func TestAnimationAPNG(t *testing.T) {
+	final, hexs := animationGrid(t)
+	anim := Animation{Format: AnimateAPNG, Delay: 50 * time.Millisecond, FramesPerRing: 3}
+
+	var buf bytes.Buffer
+	if err := anim.Encode(&buf, final, hexs); err != nil {
+		t.Fatal(err)
+	}
+
+	// Viewers without APNG support show the first frame
+	first, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	center := hexs[0].Center
+	if c := color.RGBAModel.Convert(first.At(int(center.X), int(center.Y))); c != final.At(int(center.X), int(center.Y)) {
+		t.Errorf("Expected the centre in the first frame, got %v", c)
+	}
+	if _, _, _, a := first.At(5, 5).RGBA(); a != 0 {
+		t.Errorf("Expected the rest of the first frame to be transparent")
+	}
+
+	chunks := make(map[string]int)
+	data := buf.Bytes()[8:]
+	for len(data) >= 12 {
+		n := binary.BigEndian.Uint32(data)
+		chunks[string(data[4:8])]++
+		data = data[12+n:]
+	}
+
+	if chunks["fcTL"] != 7 || chunks["fdAT"] != 6 || chunks["IDAT"] != 1 {
+		t.Errorf("Expected 7 frames, got chunks %v", chunks)
+	}
+}
@@ -0,0 +1,643 @@
+package render
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
+	"golang.org/x/sync/singleflight"
+)
+
+// CacheIndexName is the file inside the cache directory holding the index.
+const CacheIndexName = "index.json"
+
+// downloadPrefix starts the names of images being downloaded.
+const downloadPrefix = ".download-"
+
+const (
+	DefaultCacheTTL     = 7 * 24 * time.Hour // Default time before a cached image is revalidated.
+	DefaultCacheMaxSize = 512 << 20          // Default size limit of the cache in bytes.
+	DefaultFetchTimeout = time.Minute        // Time limit of a download when the client has none.
+)
+
+// CacheEntry describes a cached image.
+type CacheEntry struct {
+	URL          string    `json:"url"`                    // Full url the image was fetched from.
+	File         string    `json:"file"`                   // File name inside the cache directory.
+	ContentType  string    `json:"contentType"`            // Content-Type sent by the server.
+	ETag         string    `json:"etag,omitempty"`         // ETag sent by the server.
+	LastModified string    `json:"lastModified,omitempty"` // Last-Modified sent by the server.
+	Size         int64     `json:"size"`                   // Size of the file in bytes.
+	FetchedAt    time.Time `json:"fetchedAt"`              // Time the image was downloaded or revalidated.
+	AccessedAt   time.Time `json:"accessedAt"`             // Time the image was last used.
+}
+
+// ImageCache stores downloaded images on the disk. Files are named after the
+// hash of their full url, so urls sharing a file name don't collide.
+//
+// Concurrent requests for the same url share a single download. Downloads
+// are written to a temporary file and only moved into place once they decode
+// as an image, so an interrupted download never ends up in the cache.
+//
+// Images older than TTL are revalidated with the server before they are
+// used. When the cache grows beyond MaxSize the least recently used images
+// are evicted, except those used by a render still in progress. A zero TTL
+// or MaxSize disables expiry or eviction.
+type ImageCache struct {
+	Dir     string
+	Client  *http.Client
+	TTL     time.Duration
+	MaxSize int64
+
+	now    func() time.Time
+	flight singleflight.Group
+	mu     sync.Mutex
+	index  map[string]*CacheEntry
+	loaded bool
+	dirty  bool
+	holds  map[*time.Time]bool // Start of the renders in progress.
+}
+
+// NewImageCache creates a cache storing images in dir.
+func NewImageCache(dir string) *ImageCache {
+	return &ImageCache{
+		Dir:     dir,
+		Client:  http.DefaultClient,
+		TTL:     DefaultCacheTTL,
+		MaxSize: DefaultCacheMaxSize,
+		now:     time.Now,
+	}
+}
+
+var defaultCache = sync.OnceValues(func() (*ImageCache, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cache directory: %w", err)
+	}
+
+	return NewImageCache(filepath.Join(cacheDir, "anilist-grid", "images")), nil
+})
+
+// DefaultImageCache returns the cache used by Download.
+func DefaultImageCache() (*ImageCache, error) {
+	return defaultCache()
+}
+
+type imageCacheKey struct{}
+
+// WithImageCache returns a copy of ctx whose images are downloaded into cache
+// instead of the default cache.
+func WithImageCache(ctx context.Context, cache *ImageCache) context.Context {
+	return context.WithValue(ctx, imageCacheKey{}, cache)
+}
+
+// imageCacheFromContext returns the cache stored in ctx by WithImageCache, or
+// the default cache.
+func imageCacheFromContext(ctx context.Context) (*ImageCache, error) {
+	if cache, ok := ctx.Value(imageCacheKey{}).(*ImageCache); ok {
+		return cache, nil
+	}
+	return DefaultImageCache()
+}
+
+// hold keeps the images used from now on from being evicted until release is
+// called, so a render never loses a file between fetching and decoding it.
+func (c *ImageCache) hold() (release func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	start := c.now()
+	if c.holds == nil {
+		c.holds = make(map[*time.Time]bool)
+	}
+	c.holds[&start] = true
+
+	return func() {
+		c.mu.Lock()
+		delete(c.holds, &start)
+		c.mu.Unlock()
+	}
+}
+
+// holdImages holds the cache of ctx for a render, see ImageCache.hold.
+func holdImages(ctx context.Context) (release func()) {
+	cache, err := imageCacheFromContext(ctx)
+	if err != nil {
+		return func() {}
+	}
+	return cache.hold()
+}
+
+// Download downloads the image into the cache of ctx, see WithImageCache, and
+// returns its path. Local images are returned as they are.
+func Download(ctx context.Context, image string) (string, error) {
+	if hexgrid.IsLocal(image) {
+		if _, err := os.Stat(image); err != nil {
+			return "", fmt.Errorf("failed to find image: %w", err)
+		}
+		return image, nil
+	}
+
+	cache, err := imageCacheFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return cache.FetchContext(ctx, image)
+}
+
+// CacheKey returns the key of a url inside the cache.
+func CacheKey(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// load reads the index from the disk. Caller must hold mu.
+func (c *ImageCache) load() error {
+	if c.loaded {
+		return nil
+	}
+
+	if err := os.MkdirAll(c.Dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	c.index = make(map[string]*CacheEntry)
+
+	file, err := os.Open(filepath.Join(c.Dir, CacheIndexName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to open cache index: %w", err)
+	}
+	if err == nil {
+		defer file.Close()
+		if err := json.NewDecoder(file).Decode(&c.index); err != nil {
+			slog.Warn("Cache index is corrupted, starting a new one", "error", err)
+			c.index = make(map[string]*CacheEntry)
+		}
+	}
+
+	// Entries written before sizes were tracked
+	for _, entry := range c.index {
+		if entry.Size != 0 {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(c.Dir, entry.File)); err == nil {
+			entry.Size = info.Size()
+		}
+	}
+
+	c.loaded = true
+	return nil
+}
+
+// Save writes the index to the disk if it changed.
+func (c *ImageCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(c.index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(c.Dir, CacheIndexName+".tmp")
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return fmt.Errorf("failed to write cache index: %w", err)
+	}
+
+	if err := os.Rename(tmp, filepath.Join(c.Dir, CacheIndexName)); err != nil {
+		return fmt.Errorf("failed to write cache index: %w", err)
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// Lookup returns the index entry of a url.
+func (c *ImageCache) Lookup(rawURL string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return CacheEntry{}, false
+	}
+
+	entry, ok := c.index[CacheKey(rawURL)]
+	if !ok {
+		return CacheEntry{}, false
+	}
+	return *entry, true
+}
+
+// Fetch returns the path of the cached image, downloading it first if it
+// isn't cached yet. Expired images are revalidated, if that fails the stale
+// image is used.
+func (c *ImageCache) Fetch(rawURL string) (string, error) {
+	return c.FetchContext(context.Background(), rawURL)
+}
+
+// FetchContext is like Fetch but returns the context error when ctx is
+// cancelled. The shared download isn't cancelled with the caller that
+// started it, other callers may be waiting for it, it is limited by the
+// timeout of Client instead.
+func (c *ImageCache) FetchContext(ctx context.Context, rawURL string) (string, error) {
+	ch := c.flight.DoChan(CacheKey(rawURL), func() (any, error) {
+		timeout := c.Client.Timeout
+		if timeout <= 0 {
+			timeout = DefaultFetchTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+		return c.fetch(ctx, rawURL)
+	})
+
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return "", res.Err
+		}
+		return res.Val.(string), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (c *ImageCache) fetch(ctx context.Context, rawURL string) (string, error) {
+	c.mu.Lock()
+	if err := c.load(); err != nil {
+		c.mu.Unlock()
+		return "", err
+	}
+
+	key := CacheKey(rawURL)
+	now := c.now()
+
+	var cached *CacheEntry
+	if entry, ok := c.index[key]; ok {
+		filePath := filepath.Join(c.Dir, entry.File)
+		if _, err := os.Stat(filePath); err == nil {
+			entry.AccessedAt = now
+			c.dirty = true
+
+			if c.TTL <= 0 || now.Sub(entry.FetchedAt) < c.TTL {
+				c.mu.Unlock()
+				slog.Debug("Image already exists", "path", filePath)
+				return filePath, nil
+			}
+
+			copied := *entry
+			cached = &copied
+		} else {
+			delete(c.index, key)
+			c.dirty = true
+		}
+	}
+
+	if cached == nil {
+		if filePath, ok := c.migrate(rawURL); ok {
+			c.mu.Unlock()
+			return filePath, nil
+		}
+	}
+	c.mu.Unlock()
+
+	entry, err := c.download(ctx, rawURL, cached)
+	if err != nil {
+		if cached != nil && ctx.Err() == nil {
+			slog.Warn("Failed to revalidate image, using stale copy", "url", rawURL, "error", err)
+			return filepath.Join(c.Dir, cached.File), nil
+		}
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.index[key] = entry
+	c.dirty = true
+	c.evict(key)
+	c.mu.Unlock()
+
+	return filepath.Join(c.Dir, entry.File), nil
+}
+
+// download fetches the image. When cached is set the request is conditional
+// and a 304 response only refreshes the entry.
+func (c *ImageCache) download(ctx context.Context, rawURL string, cached *CacheEntry) (*CacheEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %w", err)
+	}
+
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	now := c.now()
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		entry := *cached
+		entry.FetchedAt = now
+		entry.AccessedAt = now
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			entry.ETag = etag
+		}
+		slog.Debug("Image not modified", "url", rawURL)
+		return &entry, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download image: status code %d", resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	entry := &CacheEntry{
+		URL:          rawURL,
+		File:         CacheKey(rawURL) + cacheExt(rawURL, contentType),
+		ContentType:  contentType,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FetchedAt:    now,
+		AccessedAt:   now,
+	}
+	filePath := filepath.Join(c.Dir, entry.File)
+
+	file, err := os.CreateTemp(c.Dir, downloadPrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	entry.Size, err = io.Copy(file, resp.Body)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save image: %w", err)
+	}
+
+	if err := verifyImage(file.Name()); err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	if err := os.Rename(file.Name(), filePath); err != nil {
+		return nil, fmt.Errorf("failed to save image: %w", err)
+	}
+
+	slog.Debug("Image downloaded successfully", "path", filePath)
+	return entry, nil
+}
+
+// verifyImage decodes the whole image at path to make sure it isn't
+// truncated or corrupted.
+func verifyImage(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, _, err = image.Decode(bufio.NewReader(file))
+	return err
+}
+
+// evict removes the least recently used images until the cache fits in
+// MaxSize. The image with the key keep is never evicted. Caller must hold mu.
+func (c *ImageCache) evict(keep string) (int, int64) {
+	if c.MaxSize <= 0 {
+		return 0, 0
+	}
+
+	var total int64
+	keys := make([]string, 0, len(c.index))
+	for key, entry := range c.index {
+		total += entry.Size
+		keys = append(keys, key)
+	}
+
+	if total <= c.MaxSize {
+		return 0, 0
+	}
+
+	slices.SortFunc(keys, func(a, b string) int {
+		return c.index[a].AccessedAt.Compare(c.index[b].AccessedAt)
+	})
+
+	// Images used since the oldest render in progress started may not be
+	// decoded yet
+	var held *time.Time
+	for start := range c.holds {
+		if held == nil || start.Before(*held) {
+			held = start
+		}
+	}
+
+	var count int
+	var freed int64
+	for _, key := range keys {
+		if total <= c.MaxSize {
+			break
+		}
+		entry := c.index[key]
+		if key == keep || (held != nil && !entry.AccessedAt.Before(*held)) {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(c.Dir, entry.File)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("Failed to evict cached image", "file", entry.File, "error", err)
+			continue
+		}
+
+		delete(c.index, key)
+		total -= entry.Size
+		freed += entry.Size
+		count++
+		c.dirty = true
+	}
+
+	return count, freed
+}
+
+// Prune removes expired images, files missing from the index and, if the
+// cache is still larger than MaxSize, the least recently used images. Files
+// of versions before the index are kept until they expire, see migrate. It
+// returns the number of removed files and the freed bytes.
+func (c *ImageCache) Prune() (int, int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return 0, 0, err
+	}
+
+	var count int
+	var freed int64
+
+	now := c.now()
+	indexed := make(map[string]bool)
+	for key, entry := range c.index {
+		if c.TTL > 0 && now.Sub(entry.FetchedAt) >= c.TTL {
+			if err := os.Remove(filepath.Join(c.Dir, entry.File)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return count, freed, err
+			}
+			delete(c.index, key)
+			count++
+			freed += entry.Size
+			c.dirty = true
+			continue
+		}
+		indexed[entry.File] = true
+	}
+
+	files, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return count, freed, err
+	}
+
+	for _, file := range files {
+		// Temporary files belong to downloads in progress
+		if file.IsDir() || file.Name() == CacheIndexName || indexed[file.Name()] || strings.HasPrefix(file.Name(), downloadPrefix) {
+			continue
+		}
+
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		if isLegacy(file.Name()) && (c.TTL <= 0 || now.Sub(info.ModTime()) < c.TTL) {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(c.Dir, file.Name())); err != nil {
+			return count, freed, err
+		}
+		count++
+		freed += info.Size()
+	}
+
+	n, f := c.evict("")
+	return count + n, freed + f, nil
+}
+
+// legacySize is the only image size versions before the index downloaded,
+// e.g. https://s4.anilist.co/file/anilistcdn/media/anime/cover/medium/1.jpg.
+const legacySize = "medium"
+
+// migrate moves an image cached by versions before the index, which named
+// files after the last element of the url, into the index. Only urls of
+// legacySize images can claim a legacy file, others sharing its name, such as
+// the large cover, are downloaded. Caller must hold mu.
+func (c *ImageCache) migrate(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || path.Base(path.Dir(u.Path)) != legacySize {
+		return "", false
+	}
+
+	name := path.Base(u.Path)
+	if !isLegacy(name) {
+		return "", false
+	}
+
+	legacy := filepath.Join(c.Dir, name)
+	info, err := os.Stat(legacy)
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+
+	// Older versions wrote straight into the final path
+	if err := verifyImage(legacy); err != nil {
+		slog.Warn("Dropping corrupted cached image", "path", legacy, "error", err)
+		os.Remove(legacy)
+		return "", false
+	}
+
+	key := CacheKey(rawURL)
+	entry := &CacheEntry{
+		URL:         rawURL,
+		File:        key + cacheExt(rawURL, ""),
+		ContentType: mime.TypeByExtension(path.Ext(name)),
+		Size:        info.Size(),
+		FetchedAt:   info.ModTime(),
+		AccessedAt:  c.now(),
+	}
+
+	filePath := filepath.Join(c.Dir, entry.File)
+	if err := os.Rename(legacy, filePath); err != nil {
+		slog.Warn("Failed to migrate cached image", "path", legacy, "error", err)
+		return "", false
+	}
+
+	c.index[key] = entry
+	c.dirty = true
+
+	slog.Debug("Migrated cached image", "from", legacy, "to", filePath)
+	return filePath, true
+}
+
+// isLegacy reports whether the file name can be one of versions before the
+// index: not the index, a download or a file named after a cache key.
+func isLegacy(name string) bool {
+	if name == "." || name == "/" || name == CacheIndexName || strings.HasPrefix(name, downloadPrefix) {
+		return false
+	}
+	key := strings.TrimSuffix(name, path.Ext(name))
+	if _, err := hex.DecodeString(key); err == nil && len(key) == 2*sha256.Size {
+		return false
+	}
+	return true
+}
+
+// cacheExt picks the file extension of a cached image from its url, falling
+// back to its content type.
+func cacheExt(rawURL, contentType string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		if ext := path.Ext(u.Path); ext != "" && len(ext) <= 5 {
+			return ext
+		}
+	}
+
+	if contentType != "" {
+		if exts, err := mime.ExtensionsByType(contentType); err == nil && len(exts) != 0 {
+			return exts[0]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,458 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/image/bmp"
+)
+
+// testPNG encodes a small image whose colour depends on seed.
+func testPNG(seed string) []byte {
+	sum := sha256.Sum256([]byte(seed))
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{sum[0], sum[1], sum[2], 255}), image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func newImageServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("ETag", `"`+r.URL.Path+`"`)
+		w.Write(testPNG(r.URL.Path))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestImageCacheKeysByFullURL(t *testing.T) {
+	server := newImageServer(t)
+	cache := NewImageCache(t.TempDir())
+
+	medium, err := cache.Fetch(server.URL + "/medium/cover.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	large, err := cache.Fetch(server.URL + "/large/cover.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if medium == large {
+		t.Fatalf("Expected different files for urls with the same base name, got %s", medium)
+	}
+
+	b, _ := os.ReadFile(large)
+	if !bytes.Equal(b, testPNG("/large/cover.png")) {
+		t.Errorf("Expected large cover content, got %q", b)
+	}
+
+	entry, ok := cache.Lookup(server.URL + "/medium/cover.png")
+	if !ok {
+		t.Fatal("Expected index entry for medium cover")
+	}
+	if entry.ContentType != "image/png" || entry.ETag != `"/medium/cover.png"` || entry.FetchedAt.IsZero() {
+		t.Errorf("Unexpected index entry %+v", entry)
+	}
+}
+
+func TestImageCacheSaveIndex(t *testing.T) {
+	server := newImageServer(t)
+	dir := t.TempDir()
+
+	cache := NewImageCache(dir)
+	if _, err := cache.Fetch(server.URL + "/a.png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := NewImageCache(dir)
+	entry, ok := reopened.Lookup(server.URL + "/a.png")
+	if !ok {
+		t.Fatal("Expected index entry after reopening the cache")
+	}
+	if entry.URL != server.URL+"/a.png" {
+		t.Errorf("Expected url %s, got %s", server.URL+"/a.png", entry.URL)
+	}
+}
+
+func TestImageCacheMigratesLegacyFiles(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "cover.png")
+	expired := filepath.Join(dir, "old.png")
+	for _, name := range []string{legacy, expired} {
+		if err := os.WriteFile(name, testPNG("legacy"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * DefaultCacheTTL)
+	if err := os.Chtimes(expired, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	server := newImageServer(t)
+	cache := NewImageCache(dir)
+
+	// Older versions only downloaded medium images, the large cover sharing
+	// the file name is downloaded
+	fetched, err := cache.Fetch(server.URL + "/large/cover.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(fetched); !bytes.Equal(b, testPNG("/large/cover.png")) {
+		t.Errorf("Expected the large cover to be downloaded, got %q", b)
+	}
+
+	// Legacy files are kept for their url until they expire
+	if _, _, err := cache.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		t.Errorf("Expected prune to keep the legacy file: %v", err)
+	}
+	if _, err := os.Stat(expired); err == nil {
+		t.Errorf("Expected prune to remove the expired legacy file")
+	}
+
+	migrated, err := cache.Fetch(server.URL + "/medium/cover.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(migrated); !bytes.Equal(b, testPNG("legacy")) {
+		t.Errorf("Expected legacy content for the medium cover, got %q", b)
+	}
+	if filepath.Base(migrated) != CacheKey(server.URL+"/medium/cover.png")+".png" {
+		t.Errorf("Expected the legacy file under the key of its url, got %s", migrated)
+	}
+	if _, err := os.Stat(legacy); err == nil {
+		t.Errorf("Expected the legacy file to be moved")
+	}
+}
+
+func TestImageCacheRevalidation(t *testing.T) {
+	var requests, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Write(testPNG("v1"))
+	}))
+	defer server.Close()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewImageCache(t.TempDir())
+	cache.TTL = time.Hour
+	cache.now = func() time.Time { return now }
+
+	url := server.URL + "/avatar.png"
+	if _, err := cache.Fetch(url); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(30 * time.Minute)
+	if _, err := cache.Fetch(url); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected fresh image to be served from cache, got %d requests", requests)
+	}
+
+	now = now.Add(time.Hour)
+	path, err := cache.Fetch(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 || notModified != 1 {
+		t.Errorf("Expected a conditional request, got %d requests and %d not modified", requests, notModified)
+	}
+
+	entry, _ := cache.Lookup(url)
+	if !entry.FetchedAt.Equal(now) {
+		t.Errorf("Expected revalidation to refresh fetch time, got %v", entry.FetchedAt)
+	}
+
+	if b, _ := os.ReadFile(path); !bytes.Equal(b, testPNG("v1")) {
+		t.Errorf("Expected cached content to be kept, got %q", b)
+	}
+
+	server.Close()
+	now = now.Add(2 * time.Hour)
+	if _, err := cache.Fetch(url); err != nil {
+		t.Errorf("Expected stale image when the server is down, got %v", err)
+	}
+}
+
+func TestImageCacheEviction(t *testing.T) {
+	server := newImageServer(t)
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewImageCache(t.TempDir())
+	cache.MaxSize = int64(len(testPNG("/a.png"))) * 5 / 2
+	cache.now = func() time.Time { return now }
+
+	paths := make(map[string]string)
+	for _, name := range []string{"a", "b", "c"} {
+		now = now.Add(time.Minute)
+		path, err := cache.Fetch(server.URL + "/" + name + ".png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths[name] = path
+	}
+
+	if _, err := os.Stat(paths["a"]); err == nil {
+		t.Errorf("Expected least recently used image to be evicted")
+	}
+	for _, name := range []string{"b", "c"} {
+		if _, err := os.Stat(paths[name]); err != nil {
+			t.Errorf("Expected %s to stay cached: %v", name, err)
+		}
+	}
+}
+
+func TestImageCacheEvictionSkipsHeldImages(t *testing.T) {
+	server := newImageServer(t)
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewImageCache(t.TempDir())
+	cache.MaxSize = int64(len(testPNG("/a.png"))) * 5 / 2
+	cache.now = func() time.Time { return now }
+
+	fetch := func(name string) string {
+		t.Helper()
+		now = now.Add(time.Minute)
+		path, err := cache.Fetch(server.URL + "/" + name + ".png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	old := fetch("old")
+
+	// A render fetches every image before decoding them
+	now = now.Add(time.Minute)
+	release := cache.hold()
+	var held []string
+	for _, name := range []string{"a", "b", "c"} {
+		held = append(held, fetch(name))
+	}
+	for _, path := range held {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected images of a render in progress to stay cached: %v", err)
+		}
+	}
+	if _, err := os.Stat(old); err == nil {
+		t.Errorf("Expected the image used before the render to be evicted")
+	}
+
+	release()
+	fetch("d")
+	if _, err := os.Stat(held[0]); err == nil {
+		t.Errorf("Expected eviction to resume once the render is done")
+	}
+}
+
+func TestImageCachePrune(t *testing.T) {
+	server := newImageServer(t)
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	cache := NewImageCache(dir)
+	cache.TTL = time.Hour
+	cache.now = func() time.Time { return now }
+
+	old, err := cache.Fetch(server.URL + "/old.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(45 * time.Minute)
+	fresh, err := cache.Fetch(server.URL + "/fresh.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Named after a key but missing from the index, not a legacy file
+	orphan := filepath.Join(dir, CacheKey("orphan")+".png")
+	if err := os.WriteFile(orphan, []byte("orphan"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	download := filepath.Join(dir, downloadPrefix+"123")
+	if err := os.WriteFile(download, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(30 * time.Minute)
+	count, _, err := cache.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 2 {
+		t.Errorf("Expected 2 removed files, got %d", count)
+	}
+	for _, path := range []string{old, orphan} {
+		if _, err := os.Stat(path); err == nil {
+			t.Errorf("Expected %s to be pruned", path)
+		}
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("Expected fresh image to stay cached: %v", err)
+	}
+	if _, err := os.Stat(download); err != nil {
+		t.Errorf("Expected a download in progress to be left alone: %v", err)
+	}
+}
+
+func TestImageCacheRejectsTruncatedDownloads(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := testPNG(r.URL.Path)
+		w.Write(b[:len(b)/2])
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cache := NewImageCache(dir)
+
+	if _, err := cache.Fetch(server.URL + "/broken.png"); err == nil {
+		t.Fatal("Expected truncated image to fail")
+	}
+
+	if _, ok := cache.Lookup(server.URL + "/broken.png"); ok {
+		t.Errorf("Expected truncated image to stay out of the index")
+	}
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 0 {
+		t.Errorf("Expected no files left behind, got %d", len(files))
+	}
+}
+
+func TestImageCacheDeduplicatesConcurrentFetches(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		w.Write(testPNG(r.URL.Path))
+	}))
+	defer server.Close()
+
+	cache := NewImageCache(t.TempDir())
+
+	var wg sync.WaitGroup
+	paths := make([]string, 8)
+	for i := range paths {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path, err := cache.Fetch(server.URL + "/shared.png")
+			if err != nil {
+				t.Error(err)
+			}
+			paths[i] = path
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Expected a single download, got %d", n)
+	}
+	for _, path := range paths[1:] {
+		if path != paths[0] {
+			t.Errorf("Expected every caller to get %s, got %s", paths[0], path)
+		}
+	}
+}
+
+func TestImageCacheFetchOutlivesCancelledCaller(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write(testPNG(r.URL.Path))
+	}))
+	defer server.Close()
+
+	cache := NewImageCache(t.TempDir())
+	url := server.URL + "/shared.png"
+
+	// The first caller starts the download and gives up while it runs
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := cache.FetchContext(ctx, url)
+		first <- err
+	}()
+	<-started
+
+	second := make(chan error, 1)
+	go func() {
+		path, err := cache.FetchContext(context.Background(), url)
+		if err == nil {
+			_, err = os.Stat(path)
+		}
+		second <- err
+	}()
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the first caller to be cancelled, got %v", err)
+	}
+
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("Expected the second caller to get the image, got %v", err)
+	}
+}
+
+func TestVerifyImageFormats(t *testing.T) {
+	dir := t.TempDir()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	var buf bytes.Buffer
+	if err := bmp.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "cover.bmp")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifyImage(path); err != nil {
+		t.Errorf("Expected BMP covers to decode without the source package, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"log/slog"
+	"sync"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+	"github.com/disintegration/imaging"
+	"github.com/fogleman/gg"
+)
+
+// DominantColor returns the most common colour of img. Colours are grouped
+// in coarse buckets and the average of the largest bucket is returned.
+// Transparent pixels are ignored.
+func DominantColor(img image.Image) color.RGBA {
+	small := imaging.Resize(img, 24, 24, imaging.Box)
+
+	type bucket struct{ r, g, b, n int }
+	var buckets [512]bucket
+	for i := 0; i < len(small.Pix); i += 4 {
+		p := small.Pix[i : i+4]
+		if p[3] < 0x80 {
+			continue
+		}
+		b := &buckets[int(p[0]>>5)<<6|int(p[1]>>5)<<3|int(p[2]>>5)]
+		b.r += int(p[0])
+		b.g += int(p[1])
+		b.b += int(p[2])
+		b.n++
+	}
+
+	best := 0
+	for i, b := range buckets {
+		if b.n > buckets[best].n {
+			best = i
+		}
+	}
+
+	b := buckets[best]
+	if b.n == 0 {
+		return hexgrid.DefaultColor
+	}
+	return color.RGBA{uint8(b.r / b.n), uint8(b.g / b.n), uint8(b.b / b.n), 0xff}
+}
+
+// FillColors computes the dominant colour of the nodes that have none from
+// their images, downloading them through the cache with workers concurrent
+// downloads.
+func FillColors(ctx context.Context, nodes []hexgrid.Node, workers int) {
+	if workers <= 0 {
+		workers = DefaultDownloads
+	}
+
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i := range nodes {
+		if nodes[i].Color != "" || nodes[i].Image == "" {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			path, err := Download(ctx, nodes[i].Image)
+			if err != nil {
+				slog.Debug("Failed to download image for its colour", "image", nodes[i].Image, "error", err)
+				return
+			}
+			img, err := gg.LoadImage(path)
+			if err != nil {
+				slog.Debug("Failed to load image for its colour", "image", nodes[i].Image, "error", err)
+				return
+			}
+
+			c := DominantColor(img)
+			nodes[i].Color = fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+		}()
+	}
+	wg.Wait()
+}
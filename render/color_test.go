@@ -0,0 +1,53 @@
+package render
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+)
+
+func TestDominantColor(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 90, 90))
+	for y := range 90 {
+		for x := range 90 {
+			c := color.RGBA{0xe0, 0x20, 0x20, 0xff}
+			if x < 30 {
+				c = color.RGBA{0x20, 0x20, 0xe0, 0xff}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+
+	if c := DominantColor(img); c.R < 0xc0 || c.B > 0x40 {
+		t.Errorf("Expected red to dominate, got %v", c)
+	}
+
+	if c := DominantColor(image.NewRGBA(image.Rect(0, 0, 10, 10))); c != hexgrid.DefaultColor {
+		t.Errorf("Expected the placeholder colour for a transparent image, got %v", c)
+	}
+}
+
+func TestFillColors(t *testing.T) {
+	dir := t.TempDir()
+	nodes := []hexgrid.Node{
+		{Image: writeTestImage(t, dir, "green.png", color.RGBA{0, 0xff, 0, 0xff})},
+		{Image: writeTestImage(t, dir, "red.png", color.RGBA{0xff, 0, 0, 0xff}), Color: "#123456"},
+		{Image: filepath.Join(dir, "missing.png")},
+	}
+
+	FillColors(context.Background(), nodes, 2)
+
+	if nodes[0].Color != "#00ff00" {
+		t.Errorf("Expected the colour of the image, got %q", nodes[0].Color)
+	}
+	if nodes[1].Color != "#123456" {
+		t.Errorf("Expected AniList's colour to be kept, got %q", nodes[1].Color)
+	}
+	if nodes[2].Color != "" {
+		t.Errorf("Expected no colour for a missing image, got %q", nodes[2].Color)
+	}
+}
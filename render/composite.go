@@ -0,0 +1,80 @@
+package render
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+)
+
+// PaintPolygon composites src over dst inside the polygon. src is aligned so
+// that its top left corner is at origin. Calls for polygons that don't
+// overlap may run concurrently.
+func PaintPolygon(dst *image.RGBA, poly hexgrid.Polygon, src image.Image, origin image.Point) {
+	clip := poly.Bounds().Intersect(dst.Bounds())
+	if clip.Empty() {
+		return
+	}
+
+	offset := src.Bounds().Min.Sub(origin)
+	nrgba, _ := src.(*image.NRGBA)
+
+	var xs []float64
+	for y := clip.Min.Y; y < clip.Max.Y; y++ {
+		xs = poly.Spans(y, clip, xs, func(x0, x1 int) {
+			row := dst.Pix[dst.PixOffset(x0, y):]
+			for x := x0; x < x1; x++ {
+				sp := image.Pt(x, y).Add(offset)
+				if !sp.In(src.Bounds()) {
+					row = row[4:]
+					continue
+				}
+
+				var r, g, b, a uint32
+				if nrgba != nil {
+					s := nrgba.Pix[nrgba.PixOffset(sp.X, sp.Y):]
+					a = uint32(s[3])
+					r, g, b = uint32(s[0])*a/0xff, uint32(s[1])*a/0xff, uint32(s[2])*a/0xff
+				} else {
+					r, g, b, a = src.At(sp.X, sp.Y).RGBA()
+					r, g, b, a = r>>8, g>>8, b>>8, a>>8
+				}
+
+				blendOver(row, r, g, b, a)
+				row = row[4:]
+			}
+		})
+	}
+}
+
+// FillPolygon composites a solid colour over dst inside the polygon.
+func FillPolygon(dst *image.RGBA, poly hexgrid.Polygon, c color.Color) {
+	clip := poly.Bounds().Intersect(dst.Bounds())
+	r, g, b, a := c.RGBA()
+	r, g, b, a = r>>8, g>>8, b>>8, a>>8
+
+	var xs []float64
+	for y := clip.Min.Y; y < clip.Max.Y; y++ {
+		xs = poly.Spans(y, clip, xs, func(x0, x1 int) {
+			row := dst.Pix[dst.PixOffset(x0, y):]
+			for range x1 - x0 {
+				blendOver(row, r, g, b, a)
+				row = row[4:]
+			}
+		})
+	}
+}
+
+// blendOver composites a premultiplied 8-bit colour over the pixel at p.
+func blendOver(p []uint8, r, g, b, a uint32) {
+	if a == 0xff {
+		p[0], p[1], p[2], p[3] = uint8(r), uint8(g), uint8(b), 0xff
+		return
+	}
+
+	inv := 0xff - a
+	p[0] = uint8(r + uint32(p[0])*inv/0xff)
+	p[1] = uint8(g + uint32(p[1])*inv/0xff)
+	p[2] = uint8(b + uint32(p[2])*inv/0xff)
+	p[3] = uint8(a + uint32(p[3])*inv/0xff)
+}
@@ -0,0 +1,124 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+	"github.com/disintegration/imaging"
+	"github.com/fogleman/gg"
+)
+
+func TestPaintPolygon(t *testing.T) {
+	dst := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	hex := hexgrid.NewHexagon(50, 50, 40, 0)
+
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	w, h := hex.Box().Size()
+	src := imaging.New(w, h, red)
+
+	PaintPolygon(dst, hex.Outline(), src, hex.Box().Rect().Min)
+
+	if c := dst.RGBAAt(50, 50); c != red {
+		t.Errorf("Expected red at the center, got %v", c)
+	}
+	if c := dst.RGBAAt(12, 16); c != (color.RGBA{}) {
+		t.Errorf("Expected corner outside the hexagon to stay empty, got %v", c)
+	}
+
+	half := color.NRGBA{0, 0, 0xff, 0x80}
+	FillPolygon(dst, hex.Outline(), half)
+	if c := dst.RGBAAt(50, 50); c.R == 0 || c.B == 0 || c.A != 0xff {
+		t.Errorf("Expected translucent fill to blend over red, got %v", c)
+	}
+}
+
+// benchmarkGrid returns a 2,000 node grid and a cover for every hexagon.
+func benchmarkGrid(b *testing.B) ([]hexgrid.Hexagon, []image.Image, int) {
+	b.Helper()
+
+	const size = 4000
+	hexs := hexgrid.GenerateHexagonRing(2000, size/2, size/2, 40)
+
+	cover := imaging.New(100, 150, color.NRGBA{0x20, 0x80, 0xc0, 0xff})
+	imgs := make([]image.Image, len(hexs))
+	for i, hex := range hexs {
+		w, h := hex.Box().Size()
+		imgs[i] = imaging.Fill(cover, w, h, imaging.Center, imaging.Box)
+	}
+
+	return hexs, imgs, size
+}
+
+// BenchmarkCompositeLocked draws every hexagon with a clipped gg context
+// under a mutex, the way rendering worked before compositing into pixels.
+func BenchmarkCompositeLocked(b *testing.B) {
+	hexs, imgs, size := benchmarkGrid(b)
+
+	for b.Loop() {
+		dc := gg.NewContext(size, size)
+		dc.SetLineWidth(5)
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, runtime.NumCPU())
+		for i, hex := range hexs {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				dc.ClearPath()
+				hex.Draw(dc)
+				dc.Clip()
+				x, y := hex.Box().Start()
+				dc.DrawImage(imgs[i], x, y)
+				dc.ResetClip()
+				hex.Draw(dc)
+				dc.Stroke()
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+// BenchmarkCompositeParallel paints the hexagons into their own pixels in
+// parallel and strokes them in a final pass.
+func BenchmarkCompositeParallel(b *testing.B) {
+	hexs, imgs, size := benchmarkGrid(b)
+
+	for b.Loop() {
+		dc := gg.NewContext(size, size)
+		dc.SetLineWidth(5)
+		canvas := dc.Image().(*image.RGBA)
+
+		jobs := make(chan int)
+		var wg sync.WaitGroup
+		for range runtime.NumCPU() {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					PaintPolygon(canvas, hexs[i].Outline(), imgs[i], hexs[i].Box().Rect().Min)
+				}
+			}()
+		}
+		for i := range hexs {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+
+		for _, hex := range hexs {
+			hex.Draw(dc)
+			dc.Stroke()
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package render
+
+import (
+	"sync"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// regularFont is the embedded font used for every text drawn on the grid.
+var regularFont = sync.OnceValue(func() *truetype.Font {
+	f, err := truetype.Parse(goregular.TTF)
+	if err != nil {
+		panic(err)
+	}
+	return f
+})
+
+// FontFace returns a face of the embedded font with the given size in points.
+func FontFace(size float64) font.Face {
+	return truetype.NewFace(regularFont(), &truetype.Options{Size: size, Hinting: font.HintingFull})
+}
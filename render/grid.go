@@ -0,0 +1,226 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"slices"
+	"time"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+	"github.com/fogleman/gg"
+)
+
+// Grid is a whole grid image: hexagons of Cell filling a Width by Height
+// area, the nodes arranged in them, and the bands above and below.
+type Grid struct {
+	Cell       hexgrid.Cell
+	Width      int
+	Height     int // Height of the grid, the bands make the image taller.
+	Layout     hexgrid.Layout
+	Order      hexgrid.Order
+	Seed       uint64         // Seed of hexgrid.OrderRandom.
+	Shape      string         // Shape of the grid, see hexgrid.ParseShape. Round when empty.
+	Anchor     hexgrid.Anchor // Where shaped grids start filling, the centre when empty.
+	MultiScale bool           // Bigger hexagons for the user, favourites and top scores.
+
+	Header *Header   // Drawn above the grid when set.
+	Footer time.Time // Date written below the grid, no footer when zero.
+
+	Renderer Renderer
+}
+
+// GridImage is a drawn grid.
+type GridImage struct {
+	Image    *image.RGBA
+	Hexagons []hexgrid.Hexagon // Hexagons of the grid, see Animation.
+	Bands    []image.Rectangle // Header and footer, outside the grid.
+	Stats    RenderStats
+}
+
+// Validate checks the options of the grid and their combinations.
+func (g Grid) Validate() error {
+	if g.Width <= 0 || g.Height <= 0 {
+		return fmt.Errorf("invalid size %dx%d", g.Width, g.Height)
+	}
+	if g.Cell.Radius <= 0 {
+		return fmt.Errorf("invalid cell size %g", g.Cell.Radius)
+	}
+
+	if g.Cell.Orientation != hexgrid.Flat && g.Cell.Orientation != hexgrid.Pointy {
+		return fmt.Errorf("invalid orientation %q, expected flat or pointy", g.Cell.Orientation)
+	}
+
+	switch g.Layout {
+	case hexgrid.LayoutRings, hexgrid.LayoutSectors, hexgrid.LayoutRect:
+	default:
+		return fmt.Errorf("invalid layout %q, expected rings, sectors or rect", g.Layout)
+	}
+	if g.Layout == hexgrid.LayoutRect && g.Shape != "" {
+		return errors.New("a shape can't be used with the rect layout")
+	}
+
+	switch g.Order {
+	case hexgrid.OrderScore, hexgrid.OrderScoreTopLeft, hexgrid.OrderRandom, hexgrid.OrderColor:
+	default:
+		return fmt.Errorf("invalid order %q, expected score, score-tl, random or color", g.Order)
+	}
+	if g.Order == hexgrid.OrderScoreTopLeft && g.Layout == hexgrid.LayoutSectors {
+		return errors.New("the score-tl order can't be used with the sectors layout")
+	}
+	if g.Order == hexgrid.OrderColor && g.Layout == hexgrid.LayoutSectors {
+		return errors.New("the color order can't be used with the sectors layout")
+	}
+
+	if g.MultiScale && (g.Layout != hexgrid.LayoutRings || g.Order != hexgrid.OrderScore || g.Shape != "") {
+		return errors.New("multi-scale only works with the rings layout, the score order and no shape")
+	}
+
+	switch g.Renderer.Stroke.Align {
+	case StrokeCenter, StrokeInner, StrokeOuter:
+	default:
+		return fmt.Errorf("invalid stroke alignment %q, expected center, inner or outer", g.Renderer.Stroke.Align)
+	}
+
+	_, _, err := g.shape()
+	return err
+}
+
+// Bounds returns the size of the image, bands included.
+func (g Grid) Bounds() image.Rectangle {
+	return image.Rect(0, 0, g.Width, g.headerHeight()+g.Height+g.footerHeight())
+}
+
+func (g Grid) headerHeight() int {
+	if g.Header == nil {
+		return 0
+	}
+	return HeaderHeight(g.Width)
+}
+
+func (g Grid) footerHeight() int {
+	if g.Footer.IsZero() {
+		return 0
+	}
+	return FooterHeight(g.Width)
+}
+
+// area returns the rectangle the grid fills, between the bands.
+func (g Grid) area() image.Rectangle {
+	return image.Rect(0, g.headerHeight(), g.Width, g.headerHeight()+g.Height)
+}
+
+// shape returns the mask of the shape of the grid, nil for round grids, and
+// the point shaped grids start filling from.
+func (g Grid) shape() (hexgrid.Mask, hexgrid.Point, error) {
+	area := g.area()
+	anchor := g.Anchor
+	if anchor == "" {
+		anchor = hexgrid.AnchorCenter
+	}
+
+	start, err := anchor.Point(area)
+	if err != nil {
+		return nil, start, err
+	}
+	if g.Order == hexgrid.OrderScoreTopLeft {
+		start = hexgrid.NewPoint(float64(area.Min.X), float64(area.Min.Y))
+	}
+
+	if g.Shape == "" {
+		return nil, start, nil
+	}
+	mask, err := hexgrid.ParseShape(g.Shape, area, g.Cell)
+	return mask, start, err
+}
+
+// hexagons generates the hexagons of the grid for the sorted nodes.
+func (g Grid) hexagons(nodes []hexgrid.Node, mask hexgrid.Mask, anchor hexgrid.Point) []hexgrid.Hexagon {
+	area := g.area()
+	cx, cy := float64(g.Width/2), float64(area.Min.Y+g.Height/2)
+
+	switch {
+	case g.Layout == hexgrid.LayoutRect:
+		return hexgrid.GenerateRect(area, anchor, g.Cell)
+	case mask != nil:
+		return hexgrid.GenerateShape(len(nodes), mask, area, anchor, g.Cell)
+	case g.MultiScale:
+		return hexgrid.GenerateScaled(hexgrid.NodeSizes(nodes), cx, cy, g.Cell)
+	default:
+		hexs := hexgrid.GenerateGrid(len(nodes), cx, cy, g.Cell)
+		if g.Order == hexgrid.OrderScoreTopLeft {
+			hexgrid.SortHexagons(hexs, anchor)
+		}
+		return hexs
+	}
+}
+
+// Draw lays out the nodes and draws the grid. nodes is left untouched. When
+// ctx is cancelled the drawing stops and returns the context error.
+func (g Grid) Draw(ctx context.Context, nodes []hexgrid.Node) (GridImage, error) {
+	var out GridImage
+	if err := g.Validate(); err != nil {
+		return out, err
+	}
+	mask, anchor, _ := g.shape()
+	defer holdImages(ctx)()
+
+	nodes = slices.Clone(nodes)
+	hexgrid.SortNodes(nodes)
+	if g.Order == hexgrid.OrderRandom {
+		hexgrid.Shuffle(nodes, g.Seed)
+	}
+
+	hexs := g.hexagons(nodes, mask, anchor)
+	if g.Order == hexgrid.OrderColor {
+		FillColors(ctx, nodes[:min(len(nodes), len(hexs))], g.Renderer.Downloads)
+	}
+	if g.Layout == hexgrid.LayoutRect {
+		var err error
+		if nodes, err = hexgrid.RepeatNodes(nodes, len(hexs)); err != nil {
+			return out, err
+		}
+	}
+	nodes = g.Order.Arrange(g.Layout, hexs, nodes)
+
+	bounds := g.Bounds()
+	dc := gg.NewContext(bounds.Dx(), bounds.Dy())
+	dc.SetStrokeStyle(gg.NewSolidPattern(color.Black))
+
+	stats, err := g.Renderer.Render(ctx, dc, hexs, nodes)
+	if err != nil {
+		return out, err
+	}
+
+	// Bands are drawn last so hexagons overflowing the grid never cover them
+	area := g.area()
+	if g.Header != nil {
+		rect := image.Rect(0, 0, g.Width, area.Min.Y)
+		g.Header.Draw(ctx, dc, rect)
+		out.Bands = append(out.Bands, rect)
+	}
+	if !g.Footer.IsZero() {
+		rect := image.Rect(0, area.Max.Y, g.Width, bounds.Max.Y)
+		DrawFooter(dc, rect, g.Footer)
+		out.Bands = append(out.Bands, rect)
+	}
+
+	out.Image = dc.Image().(*image.RGBA)
+	out.Hexagons = hexs
+	out.Stats = stats
+	return out, nil
+}
+
+// Animation returns the animation revealing img in the format.
+func (g Grid) Animation(img GridImage, format AnimationFormat, delay time.Duration, framesPerRing int) Animation {
+	return Animation{
+		Format:        format,
+		Delay:         delay,
+		FramesPerRing: framesPerRing,
+		Hold:          DefaultAnimationHold,
+		Margin:        g.Renderer.Stroke.Margin(g.Cell.Radius),
+		Static:        img.Bands,
+	}
+}
@@ -0,0 +1,118 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+)
+
+// testGrid returns a valid grid of 200x200.
+func testGrid() Grid {
+	return Grid{
+		Cell:   hexgrid.Cell{Radius: 20, Orientation: hexgrid.Flat},
+		Width:  200,
+		Height: 200,
+		Layout: hexgrid.LayoutRings,
+		Order:  hexgrid.OrderScore,
+		Renderer: Renderer{
+			Placeholder: PlaceholderNone,
+			Stroke:      Stroke{Width: 2, Align: StrokeCenter},
+		},
+	}
+}
+
+func TestGridValidate(t *testing.T) {
+	if err := testGrid().Validate(); err != nil {
+		t.Fatalf("Expected a valid grid, got %v", err)
+	}
+
+	tests := map[string]func(g *Grid){
+		"orientation":     func(g *Grid) { g.Cell.Orientation = "round" },
+		"layout":          func(g *Grid) { g.Layout = "spiral" },
+		"order":           func(g *Grid) { g.Order = "name" },
+		"stroke":          func(g *Grid) { g.Renderer.Stroke.Align = "middle" },
+		"size":            func(g *Grid) { g.Width = 0 },
+		"anchor":          func(g *Grid) { g.Anchor = "middle" },
+		"shape":           func(g *Grid) { g.Shape = "star" },
+		"rect shape":      func(g *Grid) { g.Layout, g.Shape = hexgrid.LayoutRect, "heart" },
+		"sectors from tl": func(g *Grid) { g.Layout, g.Order = hexgrid.LayoutSectors, hexgrid.OrderScoreTopLeft },
+		"colored sectors": func(g *Grid) { g.Layout, g.Order = hexgrid.LayoutSectors, hexgrid.OrderColor },
+		"scaled sectors":  func(g *Grid) { g.Layout, g.MultiScale = hexgrid.LayoutSectors, true },
+	}
+
+	for name, change := range tests {
+		g := testGrid()
+		change(&g)
+		if err := g.Validate(); err == nil {
+			t.Errorf("Expected an invalid %s to be rejected", name)
+		}
+	}
+}
+
+func TestGridDraw(t *testing.T) {
+	dir := t.TempDir()
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	nodes := []hexgrid.Node{
+		{Type: hexgrid.AnimeNode, Score: 10, Color: "#0000ff"},
+		{Type: hexgrid.UserNode, Score: 100, Image: writeTestImage(t, dir, "user.png", red)},
+		{Type: hexgrid.AnimeNode, Score: 50, Color: "#00ff00"},
+	}
+	given := slices.Clone(nodes)
+
+	g := testGrid()
+	g.Header = &Header{Name: "User"}
+	g.Footer = time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	img, err := g.Draw(context.Background(), nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, footer := HeaderHeight(200), FooterHeight(200)
+	if want := image.Rect(0, 0, 200, header+200+footer); img.Image.Bounds() != want || g.Bounds() != want {
+		t.Errorf("Expected the bands around the grid in %v, got %v", want, img.Image.Bounds())
+	}
+	if len(img.Bands) != 2 || img.Bands[1].Min.Y != header+200 {
+		t.Errorf("Expected the header and footer bands, got %v", img.Bands)
+	}
+	if len(img.Hexagons) != 3 || img.Stats.Hexagons != 3 {
+		t.Errorf("Expected a hexagon per node, got %d", len(img.Hexagons))
+	}
+	if !slices.Equal(nodes, given) {
+		t.Errorf("Expected the nodes to be left untouched")
+	}
+
+	// The best score is in the centre of the grid, below the header
+	if c := color.RGBAModel.Convert(img.Image.At(100, header+100)); c != red {
+		t.Errorf("Expected the user in the centre, got %v", c)
+	}
+}
+
+func TestGridDrawInvalid(t *testing.T) {
+	g := testGrid()
+	g.Layout = "spiral"
+	if _, err := g.Draw(context.Background(), nil); err == nil {
+		t.Errorf("Expected an invalid grid not to be drawn")
+	}
+}
+
+func TestGridDrawRectWithoutNodes(t *testing.T) {
+	g := testGrid()
+	g.Layout = hexgrid.LayoutRect
+
+	tests := map[string][]hexgrid.Node{
+		"empty":     nil,
+		"user only": {{Type: hexgrid.UserNode, Color: "#ff0000"}},
+	}
+	for name, nodes := range tests {
+		if _, err := g.Draw(context.Background(), nodes); !errors.Is(err, hexgrid.ErrNotEnoughNodes) {
+			t.Errorf("Expected ErrNotEnoughNodes for the %s list, got %v", name, err)
+		}
+	}
+}
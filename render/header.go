@@ -0,0 +1,187 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"time"
+
+	"github.com/Nadim147c/hexanilist/anilist"
+	"github.com/Nadim147c/hexanilist/hexgrid"
+	"github.com/disintegration/imaging"
+	"github.com/fogleman/gg"
+)
+
+// bandColor is the background of the header and footer.
+var bandColor = color.RGBA{0x1f, 0x22, 0x29, 0xff}
+
+// HeaderHeight returns the height of the header of an image width wide.
+func HeaderHeight(width int) int {
+	return max(width/10, 80)
+}
+
+// FooterHeight returns the height of the footer of an image width wide.
+func FooterHeight(width int) int {
+	return max(width/40, 24)
+}
+
+// Stats summarises the lists of a user.
+type Stats struct {
+	Anime          int     // Anime entries.
+	Manga          int     // Manga entries.
+	Completed      int     // Completed entries of both lists.
+	MeanScore      float64 // Mean of the scored entries, 0 when none is scored.
+	MinutesWatched int64   // Time spent watching anime, 0 when unknown.
+}
+
+// ListStats computes the stats of the lists of user.
+func ListStats(user anilist.User, anime anilist.AnimeList, manga anilist.MangaList) Stats {
+	stats := Stats{MinutesWatched: user.Statistics.Anime.MinutesWatched}
+
+	var total float64
+	var scored int
+	count := func(lists []anilist.List) int {
+		n := 0
+		for _, list := range lists {
+			for _, entry := range list.Entries {
+				n++
+				if entry.Status == anilist.Completed {
+					stats.Completed++
+				}
+				if entry.Score != nil && *entry.Score > 0 {
+					total += *entry.Score
+					scored++
+				}
+			}
+		}
+		return n
+	}
+
+	stats.Anime = count(anime.Lists)
+	stats.Manga = count(manga.Lists)
+	if scored > 0 {
+		stats.MeanScore = total / float64(scored)
+	}
+
+	return stats
+}
+
+// Fields formats the stats for the header, leaving out unknown ones.
+func (s Stats) Fields() []string {
+	fields := []string{
+		fmt.Sprintf("%d anime", s.Anime),
+		fmt.Sprintf("%d manga", s.Manga),
+		fmt.Sprintf("%d completed", s.Completed),
+	}
+	if s.MeanScore > 0 {
+		fields = append(fields, fmt.Sprintf("mean score %.1f", s.MeanScore))
+	}
+	if s.MinutesWatched > 0 {
+		fields = append(fields, fmt.Sprintf("%.1f days watched", float64(s.MinutesWatched)/(24*60)))
+	}
+	return fields
+}
+
+// Header is the band above the grid.
+type Header struct {
+	Name   string
+	Avatar string   // Path or url of the avatar, drawn on the left when set.
+	Stats  []string // Shown below the name.
+}
+
+// NewHeader creates the header of an AniList user and their lists.
+func NewHeader(user anilist.User, anime anilist.AnimeList, manga anilist.MangaList) Header {
+	return Header{
+		Name:   user.Name,
+		Avatar: string(user.Avatar.Large),
+		Stats:  ListStats(user, anime, manga).Fields(),
+	}
+}
+
+// Draw draws the header into rect. A missing avatar is replaced by the
+// initials of the name.
+func (h Header) Draw(ctx context.Context, dc *gg.Context, rect image.Rectangle) {
+	dc.Push()
+	defer dc.Pop()
+
+	drawBand(dc, rect)
+
+	height := float64(rect.Dy())
+	pad := height * 0.15
+	size := height - 2*pad
+	x := float64(rect.Min.X) + pad
+	y := float64(rect.Min.Y) + pad
+
+	if h.Avatar != "" || h.Name != "" {
+		drawAvatar(ctx, dc, h, x, y, size)
+		x += size + pad
+	}
+
+	width := float64(rect.Max.X) - pad - x
+
+	dc.SetColor(color.White)
+	dc.SetFontFace(FontFace(height * 0.28))
+	dc.DrawStringAnchored(ellipsize(dc, h.Name, width), x, y+size*0.4, 0, 0)
+
+	if len(h.Stats) != 0 {
+		dc.SetColor(color.Gray{0xc0})
+		dc.SetFontFace(FontFace(height * 0.14))
+		dc.DrawStringAnchored(ellipsize(dc, strings.Join(h.Stats, "  ·  "), width), x, y+size*0.8, 0, 0)
+	}
+}
+
+// drawAvatar draws the avatar of the header in a circle of size at (x, y).
+func drawAvatar(ctx context.Context, dc *gg.Context, h Header, x, y, size float64) {
+	r := size / 2
+	cx, cy := x+r, y+r
+
+	var img image.Image
+	if h.Avatar != "" {
+		if path, err := Download(ctx, h.Avatar); err == nil {
+			img, _ = gg.LoadImage(path)
+		}
+	}
+
+	dc.Push()
+	defer dc.Pop()
+
+	if img == nil {
+		dc.DrawCircle(cx, cy, r)
+		dc.SetColor(hexgrid.DefaultColor)
+		dc.Fill()
+		dc.SetColor(color.White)
+		dc.SetFontFace(FontFace(size * 0.4))
+		dc.DrawStringAnchored(Initials(h.Name), cx, cy, 0.5, 0.35)
+		return
+	}
+
+	img = imaging.Fill(img, int(size), int(size), imaging.Center, imaging.Lanczos)
+	dc.DrawCircle(cx, cy, r)
+	dc.Clip()
+	dc.DrawImage(img, int(x), int(y))
+	// Pop keeps the clip, the text and the footer would be clipped away
+	dc.ResetClip()
+}
+
+// DrawFooter draws the footer into rect with the date the image was
+// generated.
+func DrawFooter(dc *gg.Context, rect image.Rectangle, date time.Time) {
+	dc.Push()
+	defer dc.Pop()
+
+	drawBand(dc, rect)
+
+	height := float64(rect.Dy())
+	dc.SetColor(color.Gray{0xa0})
+	dc.SetFontFace(FontFace(height * 0.45))
+	dc.DrawStringAnchored("Generated on "+date.Format("2 January 2006"), float64(rect.Max.X)-height*0.5, float64(rect.Min.Y)+height/2, 1, 0.35)
+}
+
+// drawBand fills rect with the band colour.
+func drawBand(dc *gg.Context, rect image.Rectangle) {
+	dc.DrawRectangle(float64(rect.Min.X), float64(rect.Min.Y), float64(rect.Dx()), float64(rect.Dy()))
+	dc.SetColor(bandColor)
+	dc.Fill()
+}
@@ -0,0 +1,99 @@
+package render
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Nadim147c/hexanilist/anilist"
+	"github.com/fogleman/gg"
+)
+
+func TestListStats(t *testing.T) {
+	eight, six, zero := 8.0, 6.0, 0.0
+
+	var anime anilist.AnimeList
+	anime.Lists = []anilist.List{{Entries: []anilist.Entry{
+		{Score: &eight, Status: anilist.Completed},
+		{Score: &zero, Status: anilist.Planning},
+		{Status: anilist.Current},
+	}}}
+
+	var manga anilist.MangaList
+	manga.Lists = []anilist.List{{Entries: []anilist.Entry{{Score: &six, Status: anilist.Completed}}}}
+
+	var user anilist.User
+	user.Statistics.Anime.MinutesWatched = 3 * 24 * 60
+
+	stats := ListStats(user, anime, manga)
+	expected := Stats{Anime: 3, Manga: 1, Completed: 2, MeanScore: 7, MinutesWatched: 3 * 24 * 60}
+	if stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+
+	fields := stats.Fields()
+	if !slices.Contains(fields, "mean score 7.0") || !slices.Contains(fields, "3.0 days watched") {
+		t.Errorf("Expected mean score and days watched in %q", fields)
+	}
+
+	if fields := (Stats{Anime: 1}).Fields(); len(fields) != 3 {
+		t.Errorf("Expected unknown stats to be left out, got %q", fields)
+	}
+}
+
+func TestBandsStayInside(t *testing.T) {
+	dc := gg.NewContext(400, 600)
+	header := image.Rect(0, 0, 400, HeaderHeight(400))
+	footer := image.Rect(0, 600-FooterHeight(400), 400, 600)
+
+	Header{Name: "Lain", Stats: []string{"12 anime"}}.Draw(context.Background(), dc, header)
+	DrawFooter(dc, footer, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+
+	img := dc.Image().(*image.RGBA)
+	for y := header.Max.Y; y < footer.Min.Y; y++ {
+		for x := range 400 {
+			if img.RGBAAt(x, y).A != 0 {
+				t.Fatalf("Expected pixel %d,%d between the bands to be empty", x, y)
+			}
+		}
+	}
+
+	if img.RGBAAt(1, 1) != bandColor || img.RGBAAt(1, 599) != bandColor {
+		t.Errorf("Expected both bands to be filled")
+	}
+}
+
+func TestHeaderAvatarClip(t *testing.T) {
+	avatar := filepath.Join(t.TempDir(), "avatar.png")
+	if err := gg.NewContext(32, 32).SavePNG(avatar); err != nil {
+		t.Fatal(err)
+	}
+
+	dc := gg.NewContext(400, 600)
+	header := image.Rect(0, 0, 400, HeaderHeight(400))
+	footer := image.Rect(0, 600-FooterHeight(400), 400, 600)
+
+	Header{Name: "Lain", Avatar: avatar}.Draw(context.Background(), dc, header)
+	DrawFooter(dc, footer, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+
+	img := dc.Image().(*image.RGBA)
+	if img.RGBAAt(1, 599) != bandColor {
+		t.Errorf("Expected the footer to be drawn after an avatar")
+	}
+
+	name := false
+	for y := range header.Max.Y {
+		for x := header.Max.Y; x < 400; x++ {
+			if img.RGBAAt(x, y) == (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+				name = true
+			}
+		}
+	}
+	if !name {
+		t.Errorf("Expected the name to be drawn next to the avatar")
+	}
+}
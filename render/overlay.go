@@ -0,0 +1,190 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+
+	"github.com/Nadim147c/hexanilist/anilist"
+	"github.com/Nadim147c/hexanilist/hexgrid"
+	"github.com/fogleman/gg"
+)
+
+// MinLabelCellSize is the smallest hexagon radius labels stay legible at.
+// Smaller grids are drawn without labels.
+const MinLabelCellSize = 40
+
+// Overlay selects what is drawn over the images of the hexagons.
+type Overlay struct {
+	Labels bool // Title banner across the lower third.
+	Badges bool // Score badge and status glyph at the top.
+}
+
+var (
+	bannerColor = color.NRGBA{0, 0, 0, 0xa8}
+	badgeColor  = color.RGBA{0x20, 0x22, 0x28, 0xff}
+)
+
+// statusColors are the backgrounds of the status glyphs.
+var statusColors = map[anilist.Status]color.RGBA{
+	anilist.Current:   {0x3d, 0xb4, 0xf2, 0xff},
+	anilist.Completed: {0x4c, 0xaf, 0x50, 0xff},
+	anilist.Paused:    {0xf7, 0x9a, 0x63, 0xff},
+	anilist.Dropped:   {0xe8, 0x5d, 0x75, 0xff},
+	anilist.Planning:  {0x8b, 0x94, 0xa3, 0xff},
+	anilist.Repeating: {0x9c, 0x6a, 0xde, 0xff},
+}
+
+// Draw draws the overlays of node on its hexagon.
+func (o Overlay) Draw(dc *gg.Context, hex hexgrid.Hexagon, node hexgrid.Node) {
+	if o.Labels && node.Label != "" && hex.Extent() >= MinLabelCellSize {
+		drawBanner(dc, hex, node.Label)
+	}
+
+	if o.Badges {
+		if node.UserScore > 0 {
+			drawScoreBadge(dc, hex, node.UserScore)
+		}
+		if _, ok := statusColors[anilist.Status(node.Status)]; ok {
+			drawStatusGlyph(dc, hex, anilist.Status(node.Status))
+		}
+	}
+}
+
+// drawBanner darkens the lower third of the hexagon and writes the label on
+// it, shortened to fit.
+func drawBanner(dc *gg.Context, hex hexgrid.Hexagon, label string) {
+	box := hex.Box()
+	top := box.Y + box.H*2/3
+	banner := hex.Outline().Below(top)
+	FillPolygon(dc.Image().(*image.RGBA), banner, bannerColor)
+
+	// The banner narrows towards the bottom, fit the text to its middle
+	y := (top + box.Y + box.H) / 2
+	xs := banner.Crossings(y, nil)
+	if len(xs) < 2 {
+		return
+	}
+	width := (xs[len(xs)-1] - xs[0]) * 0.9
+
+	dc.Push()
+	defer dc.Pop()
+	dc.SetFontFace(FontFace(hex.Extent() * 0.2))
+	dc.SetColor(color.White)
+	dc.DrawStringAnchored(ellipsize(dc, label, width), hex.Center.X, y, 0.5, 0.35)
+}
+
+// ellipsize shortens s with an ellipsis until it is narrower than width.
+func ellipsize(dc *gg.Context, s string, width float64) string {
+	if w, _ := dc.MeasureString(s); w <= width {
+		return s
+	}
+
+	runes := []rune(s)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		text := string(runes) + "…"
+		if w, _ := dc.MeasureString(text); w <= width {
+			return text
+		}
+	}
+	return ""
+}
+
+// badgeCenter returns the centre and radius of the badge on the left or
+// right of the top of the hexagon. Clusters of cells get the badges of their
+// top cell.
+func badgeCenter(hex hexgrid.Hexagon, right bool) (float64, float64, float64) {
+	if hex.Size > 1 {
+		hex = hex.TopCell()
+	}
+
+	r := hex.Radius * 0.2
+	dx := hex.Radius * 0.3
+	if !right {
+		dx = -dx
+	}
+	return hex.Center.X + dx, hex.Box().Y + hex.Radius*0.3, r
+}
+
+// drawScoreBadge draws the score of the user in a circle at the top right.
+func drawScoreBadge(dc *gg.Context, hex hexgrid.Hexagon, score float64) {
+	x, y, r := badgeCenter(hex, true)
+
+	dc.Push()
+	defer dc.Pop()
+
+	dc.DrawCircle(x, y, r)
+	dc.SetColor(badgeColor)
+	dc.FillPreserve()
+	dc.SetColor(color.White)
+	dc.SetLineWidth(math.Max(r*0.12, 1))
+	dc.Stroke()
+
+	text := strconv.FormatFloat(score, 'f', -1, 64)
+	size := r
+	for ; size > 4; size *= 0.85 {
+		dc.SetFontFace(FontFace(size))
+		if w, _ := dc.MeasureString(text); w <= r*1.6 {
+			break
+		}
+	}
+	dc.DrawStringAnchored(text, x, y, 0.5, 0.35)
+}
+
+// drawStatusGlyph draws a symbol of the list status in a circle at the top
+// left.
+func drawStatusGlyph(dc *gg.Context, hex hexgrid.Hexagon, status anilist.Status) {
+	x, y, r := badgeCenter(hex, false)
+
+	dc.Push()
+	defer dc.Pop()
+
+	dc.DrawCircle(x, y, r)
+	dc.SetColor(statusColors[status])
+	dc.FillPreserve()
+	dc.SetColor(color.White)
+	dc.SetLineWidth(math.Max(r*0.12, 1))
+	dc.Stroke()
+
+	s := r * 0.45
+	dc.SetLineWidth(math.Max(r*0.18, 1))
+	dc.SetLineCapRound()
+
+	switch status {
+	case anilist.Current:
+		dc.MoveTo(x-s*0.6, y-s)
+		dc.LineTo(x+s, y)
+		dc.LineTo(x-s*0.6, y+s)
+		dc.ClosePath()
+		dc.Fill()
+	case anilist.Completed:
+		dc.MoveTo(x-s, y)
+		dc.LineTo(x-s*0.25, y+s*0.75)
+		dc.LineTo(x+s, y-s*0.6)
+		dc.Stroke()
+	case anilist.Paused:
+		dc.DrawLine(x-s*0.4, y-s*0.8, x-s*0.4, y+s*0.8)
+		dc.DrawLine(x+s*0.4, y-s*0.8, x+s*0.4, y+s*0.8)
+		dc.Stroke()
+	case anilist.Dropped:
+		dc.DrawLine(x-s*0.75, y-s*0.75, x+s*0.75, y+s*0.75)
+		dc.DrawLine(x-s*0.75, y+s*0.75, x+s*0.75, y-s*0.75)
+		dc.Stroke()
+	case anilist.Planning:
+		dc.DrawCircle(x, y, s)
+		dc.MoveTo(x, y-s*0.6)
+		dc.LineTo(x, y)
+		dc.LineTo(x+s*0.5, y)
+		dc.Stroke()
+	case anilist.Repeating:
+		dc.DrawArc(x, y, s*0.8, 0, 1.6*math.Pi)
+		dc.Stroke()
+		ax, ay := x+s*0.8, y
+		dc.MoveTo(ax-s*0.45, ay-s*0.25)
+		dc.LineTo(ax, ay+s*0.3)
+		dc.LineTo(ax+s*0.45, ay-s*0.25)
+		dc.Stroke()
+	}
+}
@@ -0,0 +1,66 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/Nadim147c/hexanilist/anilist"
+	"github.com/Nadim147c/hexanilist/hexgrid"
+	"github.com/fogleman/gg"
+)
+
+// overlayPixel draws a white hexagon of radius with the overlay and returns
+// the pixel at the bottom of its centre column.
+func overlayPixel(radius float64, overlay Overlay, node hexgrid.Node) color.RGBA {
+	size := int(radius * 3)
+	dc := gg.NewContext(size, size)
+	hex := hexgrid.NewHexagon(float64(size/2), float64(size/2), radius, 0)
+	FillPolygon(dc.Image().(*image.RGBA), hex.Outline(), color.White)
+
+	overlay.Draw(dc, hex, node)
+	return dc.Image().(*image.RGBA).RGBAAt(size/2, hex.Box().Rect().Max.Y-2)
+}
+
+func TestOverlayLabels(t *testing.T) {
+	node := hexgrid.Node{Label: "Cowboy Bebop"}
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+
+	if c := overlayPixel(60, Overlay{Labels: true}, node); c == white {
+		t.Errorf("Expected the banner to darken the lower third")
+	}
+	if c := overlayPixel(60, Overlay{}, node); c != white {
+		t.Errorf("Expected no banner when labels are off, got %v", c)
+	}
+	if c := overlayPixel(MinLabelCellSize-10, Overlay{Labels: true}, node); c != white {
+		t.Errorf("Expected no banner on small hexagons, got %v", c)
+	}
+}
+
+func TestOverlayBadges(t *testing.T) {
+	dc := gg.NewContext(200, 200)
+	hex := hexgrid.NewHexagon(100, 100, 60, 0)
+	Overlay{Badges: true}.Draw(dc, hex, hexgrid.Node{UserScore: 8.5, Status: string(anilist.Completed)})
+
+	img := dc.Image().(*image.RGBA)
+	for _, right := range []bool{false, true} {
+		x, y, _ := badgeCenter(hex, right)
+		if c := img.RGBAAt(int(x), int(y)); c.A == 0 {
+			t.Errorf("Expected a badge at %.0f,%.0f", x, y)
+		}
+	}
+}
+
+func TestEllipsize(t *testing.T) {
+	dc := gg.NewContext(10, 10)
+	dc.SetFontFace(FontFace(12))
+
+	if s := ellipsize(dc, "Bebop", 200); s != "Bebop" {
+		t.Errorf("Expected short text unchanged, got %q", s)
+	}
+
+	s := ellipsize(dc, "Neon Genesis Evangelion", 60)
+	if w, _ := dc.MeasureString(s); w > 60 || s[len(s)-len("…"):] != "…" {
+		t.Errorf("Expected text shortened with an ellipsis, got %q", s)
+	}
+}
@@ -0,0 +1,87 @@
+package render
+
+import (
+	"image/color"
+	"strings"
+	"unicode"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+	"github.com/fogleman/gg"
+)
+
+// PlaceholderText selects the text drawn on placeholder hexagons.
+type PlaceholderText string
+
+const (
+	PlaceholderNone     PlaceholderText = "none"     // Only the colour.
+	PlaceholderInitials PlaceholderText = "initials" // Initials of the label.
+	PlaceholderTitle    PlaceholderText = "title"    // The whole label, wrapped.
+)
+
+// Initials returns up to three initials of the words in s.
+func Initials(s string) string {
+	var initials []rune
+	for word := range strings.FieldsFuncSeq(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '-' || r == ':' || r == '/'
+	}) {
+		for _, r := range word {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				initials = append(initials, unicode.ToUpper(r))
+				break
+			}
+		}
+		if len(initials) == 3 {
+			break
+		}
+	}
+	return string(initials)
+}
+
+// textColor picks black or white, whichever reads better on bg.
+func textColor(bg color.RGBA) color.Color {
+	luminance := 0.2126*float64(bg.R) + 0.7152*float64(bg.G) + 0.0722*float64(bg.B)
+	if luminance > 140 {
+		return color.Black
+	}
+	return color.White
+}
+
+// drawPlaceholderText draws the text selected by mode on a placeholder.
+func drawPlaceholderText(ctx *gg.Context, hex hexgrid.Hexagon, node hexgrid.Node, mode PlaceholderText) {
+	var text string
+	switch mode {
+	case PlaceholderInitials:
+		text = Initials(node.Label)
+	case PlaceholderTitle:
+		text = node.Label
+	}
+
+	if text == "" {
+		return
+	}
+
+	ctx.Push()
+	defer ctx.Pop()
+	drawFittedText(ctx, hex, text, textColor(node.Fill()))
+}
+
+// drawFittedText draws text centred in the hexagon, wrapped and shrunk until
+// it fits inside the inner rectangle of the hexagon.
+func drawFittedText(ctx *gg.Context, hex hexgrid.Hexagon, text string, c color.Color) {
+	w, h := hex.Box().Size()
+	width, height := float64(w)*0.7, float64(h)*0.6
+
+	const lineSpacing = 1.1
+	for size := height / 2; size >= 4; size *= 0.85 {
+		ctx.SetFontFace(FontFace(size))
+
+		lines := ctx.WordWrap(text, width)
+		tw, th := ctx.MeasureMultilineString(strings.Join(lines, "\n"), lineSpacing)
+		if tw <= width && th <= height {
+			break
+		}
+	}
+
+	ctx.SetColor(c)
+	ctx.DrawStringWrapped(text, hex.Center.X, hex.Center.Y, 0.5, 0.5, width, lineSpacing, gg.AlignCenter)
+}
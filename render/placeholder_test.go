@@ -0,0 +1,20 @@
+package render
+
+import (
+	"testing"
+)
+
+func TestInitials(t *testing.T) {
+	tests := map[string]string{
+		"Cowboy Bebop":                       "CB",
+		"Fullmetal Alchemist: Brotherhood":   "FAB",
+		"Re:Zero - Starting Life in Another": "RZS",
+		"":                                   "",
+	}
+
+	for input, expected := range tests {
+		if got := Initials(input); got != expected {
+			t.Errorf("Initials(%q) = %q; want %q", input, got, expected)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Progress counts rendered hexagons and reports the progress periodically.
+type Progress struct {
+	Total int
+
+	done  atomic.Int64
+	bytes atomic.Int64
+	start time.Time
+}
+
+// NewProgress creates a progress for total hexagons.
+func NewProgress(total int) *Progress {
+	return &Progress{Total: total, start: time.Now()}
+}
+
+// Add records a rendered hexagon whose image had size bytes.
+func (p *Progress) Add(size int64) {
+	p.done.Add(1)
+	p.bytes.Add(size)
+}
+
+// String formats the progress as count, bytes and ETA.
+func (p *Progress) String() string {
+	done := p.done.Load()
+	elapsed := time.Since(p.start)
+
+	eta := "?"
+	if done > 0 && int(done) < p.Total {
+		remaining := time.Duration(float64(elapsed) / float64(done) * float64(int64(p.Total)-done))
+		eta = remaining.Round(time.Second).String()
+	} else if int(done) >= p.Total {
+		eta = "0s"
+	}
+
+	percent := 100.0
+	if p.Total > 0 {
+		percent = float64(done) / float64(p.Total) * 100
+	}
+
+	return fmt.Sprintf("[%d/%d] %3.0f%%  %.1f MiB  ETA %s", done, p.Total, percent, float64(p.bytes.Load())/(1<<20), eta)
+}
+
+// Report writes the progress to w every interval until ctx is done, then
+// writes it a final time.
+func (p *Progress) Report(ctx context.Context, w io.Writer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(w, "\r\033[K%s\n", p)
+			return
+		case <-ticker.C:
+			fmt.Fprintf(w, "\r\033[K%s", p)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Package render draws grids of hexagons: the images of the nodes clipped
+// to their hexagons, strokes, overlays, header and footer bands, and
+// animations revealing the grid.
+package render
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+	"github.com/disintegration/imaging"
+	"github.com/fogleman/gg"
+)
+
+// DefaultDownloads is the default number of concurrent image downloads.
+const DefaultDownloads = 8
+
+// Renderer draws nodes into their hexagons. Images go through three stages:
+// concurrent downloads, decoding and resizing bounded by the CPU count, and
+// compositing, so the drawing never waits on the network.
+//
+// Hexagons don't overlap, so compositing writes each image straight into the
+// pixels of its own hexagon in parallel. Strokes and text, which cross the
+// hexagon edges, are drawn with the context in a final pass. Shadows are
+// drawn before everything else.
+type Renderer struct {
+	Downloads   int             // Concurrent downloads, DefaultDownloads when 0.
+	Decoders    int             // Concurrent decoders and painters, runtime.NumCPU when 0.
+	Placeholder PlaceholderText // Text drawn on placeholders.
+	Overlay     Overlay         // Labels and badges drawn over the hexagons.
+	Stroke      Stroke          // Outline and shadow of the hexagons.
+	Progress    io.Writer       // Progress is reported here when set.
+}
+
+// RenderStats summarises a render.
+type RenderStats struct {
+	Hexagons     int // Hexagons drawn.
+	Placeholders int // Hexagons drawn as placeholders.
+}
+
+// renderJob carries a single hexagon through the stages.
+type renderJob struct {
+	index int
+	hex   hexgrid.Hexagon
+	node  hexgrid.Node
+	path  string
+	size  int64
+	img   image.Image
+	err   error
+}
+
+// Render draws the image of each node into its hexagon. Nodes whose image is
+// missing or fails to load get a placeholder instead. When ctx is cancelled
+// the render stops and returns the context error. There must be a node for
+// each hexagon.
+func (r Renderer) Render(ctx context.Context, dc *gg.Context, hexs []hexgrid.Hexagon, nodes []hexgrid.Node) (RenderStats, error) {
+	if len(nodes) < len(hexs) {
+		return RenderStats{}, fmt.Errorf("%w: %d nodes for %d hexagons", hexgrid.ErrNotEnoughNodes, len(nodes), len(hexs))
+	}
+
+	downloads := r.Downloads
+	if downloads <= 0 {
+		downloads = DefaultDownloads
+	}
+	decoders := r.Decoders
+	if decoders <= 0 {
+		decoders = runtime.NumCPU()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer holdImages(ctx)()
+
+	var progress *Progress
+	if r.Progress != nil {
+		progress = NewProgress(len(hexs))
+
+		reportCtx, stopReport := context.WithCancel(context.Background())
+		reported := make(chan struct{})
+		go func() {
+			defer close(reported)
+			progress.Report(reportCtx, r.Progress, 200*time.Millisecond)
+		}()
+		defer func() {
+			stopReport()
+			<-reported
+		}()
+	}
+
+	jobs := make(chan renderJob)
+	downloaded := make(chan renderJob, downloads)
+	decoded := make(chan renderJob, decoders)
+
+	go func() {
+		defer close(jobs)
+		for i, hex := range hexs {
+			select {
+			case jobs <- renderJob{index: i, hex: hex, node: nodes[i]}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	runStage(ctx, downloads, jobs, downloaded, downloadJob)
+	runStage(ctx, decoders, downloaded, decoded, decodeJob)
+
+	canvas := dc.Image().(*image.RGBA)
+	r.Stroke.DrawShadows(canvas, hexs)
+
+	var mu sync.Mutex
+	var stats RenderStats
+	var placeholders []renderJob
+
+	var wg sync.WaitGroup
+	for range decoders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range decoded {
+				if job.err != nil {
+					if job.node.Image != "" && ctx.Err() == nil {
+						slog.Error("Failed to render hexagon", "index", job.index, "error", job.err)
+					}
+					FillPolygon(canvas, job.hex.Outline(), job.node.Fill())
+				} else {
+					PaintPolygon(canvas, job.hex.Outline(), job.img, job.hex.Box().Rect().Min)
+				}
+
+				mu.Lock()
+				stats.Hexagons++
+				if job.err != nil {
+					stats.Placeholders++
+					placeholders = append(placeholders, job)
+				}
+				mu.Unlock()
+
+				if progress != nil {
+					progress.Add(job.size)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return stats, err
+	}
+
+	written := make(map[int]bool)
+	for _, job := range placeholders {
+		drawPlaceholderText(dc, job.hex, job.node, r.Placeholder)
+		written[job.index] = r.Placeholder != PlaceholderNone
+	}
+
+	for i, hex := range hexs {
+		overlay := r.Overlay
+		// Placeholders already show the title
+		overlay.Labels = overlay.Labels && !written[i]
+		overlay.Draw(dc, hex, nodes[i])
+	}
+
+	r.Stroke.Draw(dc, hexs, nodes)
+
+	return stats, nil
+}
+
+// runStage processes jobs from in with n workers and sends them to out. out
+// is closed once every worker is done.
+func runStage(ctx context.Context, n int, in <-chan renderJob, out chan<- renderJob, process func(context.Context, renderJob) renderJob) {
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range in {
+				if job.err == nil {
+					job = process(ctx, job)
+				}
+				select {
+				case out <- job:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+}
+
+// downloadJob downloads the image of the job.
+func downloadJob(ctx context.Context, job renderJob) renderJob {
+	if job.node.Image == "" {
+		job.err = errors.New("node has no image")
+		return job
+	}
+
+	path, err := Download(ctx, job.node.Image)
+	if err != nil {
+		job.err = fmt.Errorf("failed to download image: %w", err)
+		return job
+	}
+
+	job.path = path
+	if info, err := os.Stat(path); err == nil {
+		job.size = info.Size()
+	}
+	return job
+}
+
+// decodeJob decodes the downloaded image and crops it to the hexagon.
+func decodeJob(_ context.Context, job renderJob) renderJob {
+	img, err := gg.LoadImage(job.path)
+	if err != nil {
+		job.err = fmt.Errorf("failed to load image: %w", err)
+		return job
+	}
+
+	w, h := job.hex.Box().Size()
+	job.img = imaging.Fill(img, w, h, imaging.Center, imaging.Lanczos)
+	return job
+}
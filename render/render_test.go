@@ -0,0 +1,87 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+	"github.com/fogleman/gg"
+)
+
+// writeTestImage writes a solid image to dir and returns its path.
+func writeTestImage(t testing.TB, dir, name string, c color.Color) string {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 40, 60))
+	for y := range 60 {
+		for x := range 40 {
+			img.Set(x, y, c)
+		}
+	}
+
+	path := filepath.Join(dir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if err := png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRendererRender(t *testing.T) {
+	dir := t.TempDir()
+	red := color.RGBA{0xff, 0, 0, 0xff}
+
+	nodes := []hexgrid.Node{
+		{Image: writeTestImage(t, dir, "red.png", red)},
+		{Image: filepath.Join(dir, "missing.png"), Color: "#0000ff"},
+		{Color: "#00ff00"},
+	}
+	hexs := hexgrid.GenerateHexagonRing(len(nodes), 100, 100, 30)
+
+	dc := gg.NewContext(200, 200)
+	stats, err := Renderer{Downloads: 2, Decoders: 2}.Render(context.Background(), dc, hexs, nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Hexagons != 3 || stats.Placeholders != 2 {
+		t.Errorf("Expected 3 hexagons and 2 placeholders, got %+v", stats)
+	}
+
+	if c := color.RGBAModel.Convert(dc.Image().At(100, 100)); c != red {
+		t.Errorf("Expected image at the center hexagon, got %v", c)
+	}
+}
+
+func TestRendererRenderCancelled(t *testing.T) {
+	dir := t.TempDir()
+	nodes := []hexgrid.Node{{Image: writeTestImage(t, dir, "a.png", color.White)}}
+	hexs := hexgrid.GenerateHexagonRing(1, 50, 50, 20)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Renderer{}.Render(ctx, gg.NewContext(100, 100), hexs, nodes)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRendererRenderTooFewNodes(t *testing.T) {
+	hexs := hexgrid.GenerateHexagonRing(3, 50, 50, 20)
+	_, err := Renderer{}.Render(context.Background(), gg.NewContext(100, 100), hexs, make([]hexgrid.Node, 2))
+	if !errors.Is(err, hexgrid.ErrNotEnoughNodes) {
+		t.Errorf("Expected ErrNotEnoughNodes, got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/Nadim147c/hexanilist/anilist"
+	"github.com/Nadim147c/hexanilist/hexgrid"
+	"github.com/disintegration/imaging"
+	"github.com/fogleman/gg"
+)
+
+// StrokeAlign places the stroke relative to the outline of the hexagons.
+type StrokeAlign string
+
+const (
+	StrokeCenter StrokeAlign = "center" // Half inside, half outside.
+	StrokeInner  StrokeAlign = "inner"  // Inside the hexagon, over the image.
+	StrokeOuter  StrokeAlign = "outer"  // Outside the hexagon, into the gap.
+)
+
+// DefaultStrokeWidth is the default width of the outline of the hexagons.
+const DefaultStrokeWidth = 5
+
+// shadowColor is the colour of drop shadows before blurring.
+var shadowColor = color.NRGBA{0, 0, 0, 0x90}
+
+// Stroke is the outline drawn around each hexagon. The colour is the stroke
+// style of the context.
+type Stroke struct {
+	Width        float64     // Width of the outline, 0 for none.
+	Align        StrokeAlign // Where the outline is drawn, centred when empty.
+	DashPlanning bool        // Dash the outline of planned entries.
+	Shadow       bool        // Drop a shadow below the hexagons.
+}
+
+// inset returns how far inside the hexagon the middle of the stroke is.
+func (s Stroke) inset() float64 {
+	switch s.Align {
+	case StrokeInner:
+		return s.Width / 2
+	case StrokeOuter:
+		return -s.Width / 2
+	}
+	return 0
+}
+
+// shadow returns the offset and blur of the shadow of a hexagon of radius.
+func (s Stroke) shadow(radius float64) (float64, float64) {
+	return radius * 0.06, radius * 0.1
+}
+
+// Margin returns how far strokes and shadows reach outside a hexagon of
+// radius.
+func (s Stroke) Margin(radius float64) float64 {
+	margin := 0.0
+	if s.Width > 0 {
+		margin = s.Width/2 - s.inset()
+	}
+	if s.Shadow {
+		offset, blur := s.shadow(radius)
+		margin = max(margin, offset+3*blur)
+	}
+	return margin
+}
+
+// overlaps reports whether the strokes of neighbours separated by gap
+// cover each other.
+func (s Stroke) overlaps(gap float64) bool {
+	return 2*(s.Width/2-s.inset()) > gap
+}
+
+// dashed reports whether the outline of node is dashed.
+func (s Stroke) dashed(node hexgrid.Node) bool {
+	return s.DashPlanning && anilist.Status(node.Status) == anilist.Planning
+}
+
+// DrawShadows composites the blurred shadows of the hexagons into dst.
+func (s Stroke) DrawShadows(dst *image.RGBA, hexs []hexgrid.Hexagon) {
+	if !s.Shadow || len(hexs) == 0 {
+		return
+	}
+
+	offset, blur := s.shadow(hexs[0].Radius)
+
+	layer := image.NewRGBA(dst.Bounds())
+	for _, hex := range hexs {
+		FillPolygon(layer, hex.Outline().Translate(offset, offset), shadowColor)
+	}
+
+	blurred := imaging.Blur(layer, blur)
+	draw.Draw(dst, dst.Bounds(), blurred, blurred.Bounds().Min, draw.Over)
+}
+
+// Draw strokes the hexagons. Where strokes of neighbours overlap, the edge
+// between a solid and a dashed hexagon is left to the dashed one so the
+// dashes stay visible.
+func (s Stroke) Draw(dc *gg.Context, hexs []hexgrid.Hexagon, nodes []hexgrid.Node) {
+	if s.Width <= 0 || len(hexs) == 0 {
+		return
+	}
+
+	dc.Push()
+	defer dc.Pop()
+	dc.SetLineWidth(s.Width)
+
+	var grid *hexgrid.Grid
+	index := make(map[string]int)
+	if s.DashPlanning && s.overlaps(hexs[0].Gap) {
+		grid = hexgrid.NewHexGrid(hexs[0])
+		for i, hex := range hexs {
+			for _, c := range hex.Cells() {
+				index[grid.Key(c.X, c.Y)] = i
+			}
+		}
+	}
+
+	var dashed []hexgrid.Hexagon
+	for i, hex := range hexs {
+		if s.dashed(nodes[i]) {
+			dashed = append(dashed, hex.Inset(s.inset()))
+			continue
+		}
+
+		var skip [6]bool
+		if grid != nil && hex.Size <= 1 {
+			for e, p := range hex.Neiboors() {
+				j, ok := index[grid.Key(p.X, p.Y)]
+				skip[e] = ok && s.dashed(nodes[j])
+			}
+		}
+
+		strokeEdges(dc, hex.Inset(s.inset()), skip)
+	}
+
+	dc.SetDash(s.Width*2, s.Width*1.5)
+	for _, hex := range dashed {
+		hex.Draw(dc)
+		dc.Stroke()
+	}
+}
+
+// strokeEdges strokes the edges of the hexagon that aren't skipped, joining
+// consecutive edges into one path. Clusters of cells are always stroked
+// whole.
+func strokeEdges(dc *gg.Context, hex hexgrid.Hexagon, skip [6]bool) {
+	start := 0
+	for start < 6 && !skip[start] {
+		start++
+	}
+	if start == 6 || hex.Size > 1 {
+		hex.Draw(dc)
+		dc.Stroke()
+		return
+	}
+
+	dc.ClearPath()
+	drawing := false
+	for k := 1; k <= 6; k++ {
+		e := (start + k) % 6
+		if skip[e] {
+			drawing = false
+			continue
+		}
+
+		points := hex.Edge(e)
+		if !drawing {
+			dc.MoveTo(points[0].Value())
+			drawing = true
+		}
+		for _, p := range points[1:] {
+			dc.LineTo(p.Value())
+		}
+	}
+	dc.Stroke()
+}
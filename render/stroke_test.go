@@ -0,0 +1,116 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/Nadim147c/hexanilist/anilist"
+	"github.com/Nadim147c/hexanilist/hexgrid"
+	"github.com/fogleman/gg"
+)
+
+func TestStrokeMargin(t *testing.T) {
+	tests := []struct {
+		stroke   Stroke
+		expected float64
+	}{
+		{Stroke{Width: 4}, 2},
+		{Stroke{Width: 4, Align: StrokeInner}, 0},
+		{Stroke{Width: 4, Align: StrokeOuter}, 4},
+		{Stroke{}, 0},
+	}
+
+	for _, tt := range tests {
+		if m := tt.stroke.Margin(50); m != tt.expected {
+			t.Errorf("Margin of %+v = %f; want %f", tt.stroke, m, tt.expected)
+		}
+	}
+
+	if m := (Stroke{Shadow: true}).Margin(50); m <= 0 {
+		t.Errorf("Expected the shadow to reach outside the hexagon")
+	}
+}
+
+// strokedPixel strokes two neighbours and returns the pixel in the middle of
+// their shared edge.
+func strokedPixel(stroke Stroke, second hexgrid.Node) color.RGBA {
+	hexs := hexgrid.GenerateHexagonRing(2, 100, 100, 40)
+	nodes := []hexgrid.Node{{}, second}
+
+	dc := gg.NewContext(200, 200)
+	dc.SetColor(color.Black)
+	stroke.Draw(dc, hexs, nodes)
+
+	a, b := hexs[0].Center, hexs[1].Center
+	return dc.Image().(*image.RGBA).RGBAAt(int((a.X+b.X)/2), int((a.Y+b.Y)/2))
+}
+
+func TestStrokeDraw(t *testing.T) {
+	if c := strokedPixel(Stroke{Width: 4}, hexgrid.Node{}); c.A == 0 {
+		t.Errorf("Expected the shared edge to be stroked")
+	}
+	if c := strokedPixel(Stroke{}, hexgrid.Node{}); c.A != 0 {
+		t.Errorf("Expected no stroke with width 0, got %v", c)
+	}
+}
+
+func TestStrokeAlign(t *testing.T) {
+	hex := hexgrid.NewHexagon(50, 50, 30, 0)
+
+	// A pixel just outside the left corner
+	outside := func(align StrokeAlign) uint8 {
+		dc := gg.NewContext(100, 100)
+		dc.SetColor(color.Black)
+		Stroke{Width: 6, Align: align}.Draw(dc, []hexgrid.Hexagon{hex}, []hexgrid.Node{{}})
+		return dc.Image().(*image.RGBA).RGBAAt(17, 50).A
+	}
+
+	if a := outside(StrokeInner); a != 0 {
+		t.Errorf("Expected inner stroke to stay inside, got alpha %d", a)
+	}
+	if a := outside(StrokeOuter); a == 0 {
+		t.Errorf("Expected outer stroke to reach outside")
+	}
+}
+
+func TestStrokeDashPlanning(t *testing.T) {
+	hexs := hexgrid.GenerateHexagonRing(2, 100, 100, 40)
+	nodes := []hexgrid.Node{{}, {Status: string(anilist.Planning)}}
+
+	count := func(stroke Stroke) int {
+		dc := gg.NewContext(200, 200)
+		dc.SetColor(color.Black)
+		stroke.Draw(dc, hexs, nodes)
+
+		n := 0
+		img := dc.Image().(*image.RGBA)
+		for i := 3; i < len(img.Pix); i += 4 {
+			if img.Pix[i] != 0 {
+				n++
+			}
+		}
+		return n
+	}
+
+	solid := count(Stroke{Width: 4})
+	dashed := count(Stroke{Width: 4, DashPlanning: true})
+	if dashed >= solid {
+		t.Errorf("Expected dashes to stroke fewer pixels, got %d dashed and %d solid", dashed, solid)
+	}
+}
+
+func TestStrokeShadows(t *testing.T) {
+	hexs := hexgrid.GenerateHexagonRing(1, 50, 50, 20)
+	dst := image.NewRGBA(image.Rect(0, 0, 100, 100))
+
+	Stroke{}.DrawShadows(dst, hexs)
+	if dst.RGBAAt(50, 50).A != 0 {
+		t.Errorf("Expected no shadow when disabled")
+	}
+
+	Stroke{Shadow: true}.DrawShadows(dst, hexs)
+	if dst.RGBAAt(50, 50).A == 0 {
+		t.Errorf("Expected a shadow below the hexagon")
+	}
+}
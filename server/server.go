@@ -0,0 +1,494 @@
+// Package server serves grids of AniList users over HTTP, rendered on demand
+// so an image embedded in a README or a forum signature stays up to date.
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/png"
+	"log/slog"
+	"maps"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nadim147c/hexanilist/anilist"
+	"github.com/Nadim147c/hexanilist/hexgrid"
+	"github.com/Nadim147c/hexanilist/render"
+	"github.com/Nadim147c/hexanilist/source"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	DefaultTTL        = 30 * time.Minute // Default time a rendered grid is served for.
+	DefaultMaxRenders = 2                // Default number of grids rendered at once.
+	DefaultMaxSize    = 4000             // Default largest width or height of a grid.
+	DefaultTimeout    = 2 * time.Minute  // Default time limit of a render.
+	DefaultMaxQueue   = 16               // Default number of renders waiting for a slot.
+	DefaultCacheSize  = 64 << 20         // Default size limit of the rendered grids in bytes.
+
+	// MinCell is the smallest cell size a request can ask for. Together with
+	// MaxSize it limits the hexagons of a grid.
+	MinCell = 10
+	// MaxStroke is the widest stroke a request can ask for. The gap and the
+	// corner can't be larger than the cell.
+	MaxStroke = 20
+)
+
+// Handler serves the grid of an AniList user at /u/<name>.png. Query
+// parameters override the options of the grid, e.g. /u/Nadim.png?cell=60.
+//
+// Rendered grids are kept for TTL and sent with an ETag, so clients can
+// revalidate them cheaply. When they take more than CacheSize bytes the least
+// recently used grids are dropped.
+//
+// Requests for the same grid share a single render, and at most MaxRenders
+// grids are rendered at once, so a huge list never holds up the cached grids
+// of other users. When MaxQueue renders are already waiting for a slot, new
+// ones are turned away with 503 Service Unavailable.
+type Handler struct {
+	Grid       render.Grid // Options of the grid before the query parameters.
+	TTL        time.Duration
+	CacheSize  int64
+	MaxRenders int
+	MaxQueue   int
+	MaxSize    int
+	Timeout    time.Duration
+
+	ctx       context.Context
+	endpoints anilist.Endpoints
+	mux       *http.ServeMux
+	now       func() time.Time
+	flight    singleflight.Group
+	slots     chan struct{}
+	slotsOnce sync.Once
+
+	mu      sync.Mutex
+	grids   map[string]*entry
+	size    int64 // Bytes of the grids.
+	waiting int   // Renders waiting for a slot.
+}
+
+// entry is a rendered grid.
+type entry struct {
+	body     []byte
+	etag     string
+	expires  time.Time
+	accessed time.Time
+}
+
+// errBusy is returned when too many renders are waiting for a slot.
+var errBusy = errors.New("too many renders waiting")
+
+// New creates a handler rendering grid for users fetched from endpoints.
+// Requests to AniList and image downloads use the HTTP client and image
+// cache of ctx, renders are stopped when it is cancelled.
+func New(ctx context.Context, grid render.Grid, endpoints anilist.Endpoints) *Handler {
+	h := &Handler{
+		Grid:       grid,
+		TTL:        DefaultTTL,
+		CacheSize:  DefaultCacheSize,
+		MaxRenders: DefaultMaxRenders,
+		MaxQueue:   DefaultMaxQueue,
+		MaxSize:    DefaultMaxSize,
+		Timeout:    DefaultTimeout,
+		ctx:        ctx,
+		endpoints:  endpoints,
+		mux:        http.NewServeMux(),
+		now:        time.Now,
+		grids:      make(map[string]*entry),
+	}
+	h.mux.HandleFunc("GET /u/{file}", h.serveGrid)
+	return h
+}
+
+// ServeHTTP serves the grid of the requested user.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) serveGrid(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(r.PathValue("file"), ".png")
+	if !ok || name == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	grid, err := h.parse(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Only the parameters in use are part of the key, url.Values sorts them
+	used := make(url.Values)
+	for _, p := range params {
+		if v, ok := query[p]; ok {
+			used[p] = v
+		}
+	}
+	key := strings.ToLower(name) + "?" + used.Encode()
+
+	e, err := h.grid(r.Context(), key, name, grid)
+	if err != nil {
+		status := http.StatusBadGateway
+		switch {
+		case errors.Is(err, anilist.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, hexgrid.ErrNotEnoughNodes):
+			// The lists of the user can't fill the layout
+			status = http.StatusUnprocessableEntity
+		case errors.Is(err, context.DeadlineExceeded):
+			status = http.StatusGatewayTimeout
+		case errors.Is(err, errBusy):
+			w.Header().Set("Retry-After", "10")
+			status = http.StatusServiceUnavailable
+		case r.Context().Err() != nil:
+			return
+		}
+		slog.Error("Failed to render grid", "user", name, "error", err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	maxAge := max(int(e.expires.Sub(h.now()).Seconds()), 0)
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("ETag", e.etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(e.body))
+}
+
+// grid returns the grid of key, rendering it when it isn't cached or has
+// expired. The render carries on when ctx is cancelled, other requests may
+// be waiting for it.
+func (h *Handler) grid(ctx context.Context, key, name string, grid render.Grid) (*entry, error) {
+	h.mu.Lock()
+	e, ok := h.grids[key]
+	if ok {
+		e.accessed = h.now()
+	}
+	h.mu.Unlock()
+	if ok && h.now().Before(e.expires) {
+		return e, nil
+	}
+
+	ch := h.flight.DoChan(key, func() (any, error) {
+		body, err := h.render(name, grid)
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(body)
+		now := h.now()
+		e := &entry{body: body, etag: `"` + hex.EncodeToString(sum[:16]) + `"`, expires: now.Add(h.TTL), accessed: now}
+		h.store(key, e)
+		return e, nil
+	})
+
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*entry), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// store caches e under key. Expired grids are dropped, then the least
+// recently used ones until e fits in CacheSize. Grids larger than CacheSize
+// are never cached.
+func (h *Handler) store(key string, e *entry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(key)
+	size := int64(len(e.body))
+	now := h.now()
+	if !now.Before(e.expires) || size > h.CacheSize {
+		return
+	}
+
+	for k, old := range h.grids {
+		if !now.Before(old.expires) {
+			h.remove(k)
+		}
+	}
+
+	if h.size+size > h.CacheSize {
+		keys := slices.Collect(maps.Keys(h.grids))
+		slices.SortFunc(keys, func(a, b string) int {
+			return h.grids[a].accessed.Compare(h.grids[b].accessed)
+		})
+		for _, k := range keys {
+			if h.size+size <= h.CacheSize {
+				break
+			}
+			h.remove(k)
+		}
+	}
+
+	h.grids[key] = e
+	h.size += size
+}
+
+// remove drops the grid of key. h.mu must be held.
+func (h *Handler) remove(key string) {
+	if e, ok := h.grids[key]; ok {
+		h.size -= int64(len(e.body))
+		delete(h.grids, key)
+	}
+}
+
+// acquire takes a render slot, waiting for one unless MaxQueue renders are
+// already waiting.
+func (h *Handler) acquire(ctx context.Context) error {
+	h.slotsOnce.Do(func() { h.slots = make(chan struct{}, max(h.MaxRenders, 1)) })
+
+	select {
+	case h.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	h.mu.Lock()
+	if h.waiting >= h.MaxQueue {
+		h.mu.Unlock()
+		return errBusy
+	}
+	h.waiting++
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		h.waiting--
+		h.mu.Unlock()
+	}()
+
+	select {
+	case h.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// render fetches the user and their lists and renders their grid as a PNG,
+// taking a render slot first.
+func (h *Handler) render(name string, grid render.Grid) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(h.ctx, h.Timeout)
+	defer cancel()
+
+	if err := h.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer func() { <-h.slots }()
+
+	start := time.Now()
+	client := anilist.NewPublic(ctx, h.endpoints)
+	u, err := client.GetUser(name)
+	if err != nil {
+		return nil, err
+	}
+	user := u.Data.User
+
+	anime, manga, err := client.GetList(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := source.Anilist{User: user, Anime: anime, Manga: manga}.Nodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if grid.Header != nil {
+		header := render.NewHeader(user, anime, manga)
+		grid.Header = &header
+	}
+	if grid.Order == hexgrid.OrderRandom && grid.Seed == 0 {
+		grid.Seed = uint64(time.Now().UnixNano())
+	}
+
+	img, err := grid.Draw(ctx, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img.Image); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Rendered grid", "user", user.Name, "hexagons", img.Stats.Hexagons, "took", time.Since(start))
+	return buf.Bytes(), nil
+}
+
+// params are the query parameters of a grid.
+var params = []string{
+	"cell", "size", "width", "height", "orientation", "gap", "corner",
+	"layout", "order", "seed", "shape", "anchor", "multi-scale",
+	"stroke-width", "stroke-align", "dash-planning", "shadow",
+	"placeholder-text", "labels", "badges", "header", "footer",
+}
+
+// shapes are the shapes a request can ask for. Masks are files on the
+// server, they can only be set by its options.
+var shapes = []string{"", "hexagon", "rectangle", "heart"}
+
+// parse returns the grid of the handler with the query parameters applied.
+func (h *Handler) parse(query url.Values) (render.Grid, error) {
+	g := h.Grid
+	p := parser{query: query}
+
+	size := 0
+	p.int("size", &size)
+	if size != 0 {
+		g.Width, g.Height = size, size
+	}
+	p.float("cell", &g.Cell.Radius)
+	stringParam(&p, "orientation", &g.Cell.Orientation)
+	p.float("gap", &g.Cell.Gap)
+	p.float("corner", &g.Cell.Corner)
+	stringParam(&p, "layout", &g.Layout)
+	stringParam(&p, "order", &g.Order)
+	p.uint("seed", &g.Seed)
+	stringParam(&p, "anchor", &g.Anchor)
+	p.bool("multi-scale", &g.MultiScale)
+	p.float("stroke-width", &g.Renderer.Stroke.Width)
+	stringParam(&p, "stroke-align", &g.Renderer.Stroke.Align)
+	p.bool("dash-planning", &g.Renderer.Stroke.DashPlanning)
+	p.bool("shadow", &g.Renderer.Stroke.Shadow)
+	stringParam(&p, "placeholder-text", &g.Renderer.Placeholder)
+	p.bool("labels", &g.Renderer.Overlay.Labels)
+	p.bool("badges", &g.Renderer.Overlay.Badges)
+
+	// Only the rect layout may be wider or taller than it is high
+	if g.Layout == hexgrid.LayoutRect {
+		p.int("width", &g.Width)
+		p.int("height", &g.Height)
+	}
+
+	if query.Has("shape") {
+		g.Shape = query.Get("shape")
+		if !slices.Contains(shapes, g.Shape) {
+			return g, fmt.Errorf("invalid shape %q, expected hexagon, rectangle or heart", g.Shape)
+		}
+	}
+
+	header, footer := g.Header != nil, !g.Footer.IsZero()
+	p.bool("header", &header)
+	p.bool("footer", &footer)
+	g.Header, g.Footer = nil, time.Time{}
+	if header {
+		// The header is filled in once the user is fetched
+		g.Header = &render.Header{}
+	}
+	if footer {
+		g.Footer = h.now()
+	}
+
+	if p.err != nil {
+		return g, p.err
+	}
+	if g.Width > h.MaxSize || g.Height > h.MaxSize {
+		return g, fmt.Errorf("size %dx%d is larger than %d", g.Width, g.Height, h.MaxSize)
+	}
+	if g.Cell.Radius < MinCell {
+		return g, fmt.Errorf("cell %g is smaller than %d", g.Cell.Radius, MinCell)
+	}
+	if g.Renderer.Stroke.Width > MaxStroke {
+		return g, fmt.Errorf("stroke width %g is larger than %d", g.Renderer.Stroke.Width, MaxStroke)
+	}
+	if g.Cell.Gap > g.Cell.Radius || g.Cell.Corner > g.Cell.Radius {
+		return g, fmt.Errorf("gap %g and corner %g can't be larger than the cell %g", g.Cell.Gap, g.Cell.Corner, g.Cell.Radius)
+	}
+	return g, g.Validate()
+}
+
+// parser reads query parameters, keeping the first error.
+type parser struct {
+	query url.Values
+	err   error
+}
+
+// value returns the parameter name, false when it is missing or an earlier
+// parameter was invalid.
+func (p *parser) value(name string) (string, bool) {
+	if p.err != nil || !p.query.Has(name) {
+		return "", false
+	}
+	return p.query.Get(name), true
+}
+
+func (p *parser) fail(name, value string, err error) {
+	p.err = fmt.Errorf("invalid %s %q: %w", name, value, errors.Unwrap(err))
+}
+
+func (p *parser) int(name string, v *int) {
+	if s, ok := p.value(name); ok {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			p.fail(name, s, err)
+			return
+		}
+		*v = n
+	}
+}
+
+func (p *parser) uint(name string, v *uint64) {
+	if s, ok := p.value(name); ok {
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			p.fail(name, s, err)
+			return
+		}
+		*v = n
+	}
+}
+
+// float reads a size in pixels, which can't be negative.
+func (p *parser) float(name string, v *float64) {
+	if s, ok := p.value(name); ok {
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			p.fail(name, s, err)
+			return
+		}
+		if n < 0 || math.IsNaN(n) || math.IsInf(n, 0) {
+			p.err = fmt.Errorf("invalid %s %q", name, s)
+			return
+		}
+		*v = n
+	}
+}
+
+func (p *parser) bool(name string, v *bool) {
+	if s, ok := p.value(name); ok {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			p.fail(name, s, err)
+			return
+		}
+		*v = b
+	}
+}
+
+// stringParam sets v to the parameter name. The value is checked by
+// render.Grid.Validate.
+func stringParam[T ~string](p *parser, name string, v *T) {
+	if s, ok := p.value(name); ok {
+		*v = T(s)
+	}
+}
@@ -0,0 +1,300 @@
+package server
+
+import (
+	"context"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nadim147c/hexanilist/anilist"
+	"github.com/Nadim147c/hexanilist/hexgrid"
+	"github.com/Nadim147c/hexanilist/internal/fakeanilist"
+	"github.com/Nadim147c/hexanilist/render"
+	"golang.org/x/oauth2"
+)
+
+// newTestHandler returns a handler fetching users from a fake AniList server.
+func newTestHandler(t *testing.T) (*Handler, *fakeanilist.Server) {
+	t.Helper()
+
+	srv := fakeanilist.New()
+	t.Cleanup(srv.Close)
+
+	cfg := anilist.DefaultHTTPConfig()
+	cfg.Retries = 0
+	cfg.Rewrites = map[string]string{fakeanilist.CDN: srv.URL}
+	client, err := anilist.NewHTTPClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := render.NewImageCache(t.TempDir())
+	cache.Client = client
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client)
+	ctx = render.WithImageCache(ctx, cache)
+
+	grid := render.Grid{
+		Cell:   hexgrid.Cell{Radius: 30, Orientation: hexgrid.Flat},
+		Width:  300,
+		Height: 300,
+		Layout: hexgrid.LayoutRings,
+		Order:  hexgrid.OrderScore,
+		Renderer: render.Renderer{
+			Placeholder: render.PlaceholderNone,
+			Stroke:      render.Stroke{Width: 2, Align: render.StrokeCenter},
+		},
+	}
+	return New(ctx, grid, anilist.Endpoints{GraphQL: srv.GraphQL()}), srv
+}
+
+func get(h http.Handler, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandlerServesGrid(t *testing.T) {
+	h, srv := newTestHandler(t)
+	target := "/u/" + srv.UserName() + ".png?cell=20&size=200"
+
+	rec := get(h, target, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Expected a PNG, got %q", ct)
+	}
+
+	img, err := png.Decode(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 200 {
+		t.Errorf("Expected the size of the query, got %v", b)
+	}
+
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag")
+	}
+
+	rec = get(h, target, http.Header{"If-None-Match": {etag}})
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for a matching ETag, got %d", rec.Code)
+	}
+
+	// The name is case insensitive and the order of parameters doesn't matter
+	get(h, "/u/"+strings.ToUpper(srv.UserName())+".png?size=200&cell=20", nil)
+	if n := srv.Requests("User"); n != 1 {
+		t.Errorf("Expected the grid to be rendered once, got %d user queries", n)
+	}
+
+	rec = get(h, "/u/"+srv.UserName()+".png?cell=40&size=200", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("Expected other parameters to render another grid, got %d", rec.Code)
+	}
+}
+
+func TestHandlerTTL(t *testing.T) {
+	h, srv := newTestHandler(t)
+	now := time.Now()
+	h.now = func() time.Time { return now }
+	target := "/u/" + srv.UserName() + ".png"
+
+	get(h, target, nil)
+	now = now.Add(h.TTL - time.Second)
+	if rec := get(h, target, nil); rec.Header().Get("Cache-Control") != "public, max-age=1" {
+		t.Errorf("Expected the remaining TTL in Cache-Control, got %q", rec.Header().Get("Cache-Control"))
+	}
+	if n := srv.Requests("User"); n != 1 {
+		t.Errorf("Expected the cached grid before the TTL, got %d user queries", n)
+	}
+
+	now = now.Add(time.Second)
+	get(h, target, nil)
+	if n := srv.Requests("User"); n != 2 {
+		t.Errorf("Expected the grid to be rendered again after the TTL, got %d user queries", n)
+	}
+}
+
+func TestHandlerSharesRenders(t *testing.T) {
+	h, srv := newTestHandler(t)
+	h.MaxRenders = 1
+	target := "/u/" + srv.UserName() + ".png"
+
+	var wg sync.WaitGroup
+	codes := make([]int, 8)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = get(h, target, nil).Code
+		}()
+	}
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Expected request %d to succeed, got %d", i, code)
+		}
+	}
+	if n := srv.Requests("User"); n != 1 {
+		t.Errorf("Expected concurrent requests to share a render, got %d user queries", n)
+	}
+}
+
+func TestHandlerRenderSlots(t *testing.T) {
+	h, srv := newTestHandler(t)
+	h.MaxRenders = 1
+	h.Timeout = 50 * time.Millisecond
+
+	// Every slot is taken by a render that never ends
+	h.slotsOnce.Do(func() { h.slots = make(chan struct{}, 1) })
+	h.slots <- struct{}{}
+
+	rec := get(h, "/u/"+srv.UserName()+".png", nil)
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504 while waiting for a slot, got %d", rec.Code)
+	}
+	if n := srv.Requests("User"); n != 0 {
+		t.Errorf("Expected no queries without a slot, got %d", n)
+	}
+}
+
+func TestHandlerRenderQueue(t *testing.T) {
+	h, srv := newTestHandler(t)
+	h.MaxRenders = 1
+	h.MaxQueue = 0
+
+	h.slotsOnce.Do(func() { h.slots = make(chan struct{}, 1) })
+	h.slots <- struct{}{}
+
+	rec := get(h, "/u/"+srv.UserName()+".png", nil)
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 503 with Retry-After when the queue is full, got %d", rec.Code)
+	}
+	if n := srv.Requests("User"); n != 0 {
+		t.Errorf("Expected no queries without a slot, got %d", n)
+	}
+}
+
+func TestHandlerCacheSize(t *testing.T) {
+	h, srv := newTestHandler(t)
+	now := time.Now()
+	h.now = func() time.Time { return now }
+	user := "/u/" + srv.UserName() + ".png"
+
+	// Each parameter is another cache key for the same image
+	a, b, c := user, user+"?dash-planning=false", user+"?shadow=false"
+	fetch := func(target string) {
+		t.Helper()
+		now = now.Add(time.Second)
+		if rec := get(h, target, nil); rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200 for %s, got %d", target, rec.Code)
+		}
+	}
+
+	fetch(a)
+	h.CacheSize = h.size * 2
+
+	fetch(b)
+	fetch(a) // a is now used more recently than b
+	fetch(c)
+	if n := srv.Requests("User"); n != 3 {
+		t.Fatalf("Expected 3 renders, got %d", n)
+	}
+	if h.size > h.CacheSize || len(h.grids) != 2 {
+		t.Errorf("Expected 2 grids within %d bytes, got %d grids of %d bytes", h.CacheSize, len(h.grids), h.size)
+	}
+
+	fetch(a)
+	if n := srv.Requests("User"); n != 3 {
+		t.Errorf("Expected the recently used grid to be kept, got %d renders", n)
+	}
+	fetch(b)
+	if n := srv.Requests("User"); n != 4 {
+		t.Errorf("Expected the least recently used grid to be dropped, got %d renders", n)
+	}
+
+	h.CacheSize = 1
+	fetch(user + "?labels=false")
+	if _, ok := h.grids[strings.ToLower(srv.UserName())+"?labels=false"]; ok {
+		t.Errorf("Expected a grid larger than the cache not to be stored")
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	h, srv := newTestHandler(t)
+	name := srv.UserName()
+
+	tests := map[string]int{
+		"/u/nobody.png":                                    http.StatusNotFound,
+		"/u/" + name + ".jpg":                              http.StatusNotFound,
+		"/u/" + name + ".png?cell=abc":                     http.StatusBadRequest,
+		"/u/" + name + ".png?cell=2":                       http.StatusBadRequest,
+		"/u/" + name + ".png?gap=-5":                       http.StatusBadRequest,
+		"/u/" + name + ".png?size=100000":                  http.StatusBadRequest,
+		"/u/" + name + ".png?layout=oops":                  http.StatusBadRequest,
+		"/u/" + name + ".png?shape=/etc/passwd":            http.StatusBadRequest,
+		"/u/" + name + ".png?layout=sectors&order=color":   http.StatusBadRequest,
+		"/u/" + name + ".png?stroke-width=500":             http.StatusBadRequest,
+		"/u/" + name + ".png?gap=100000":                   http.StatusBadRequest,
+		"/u/" + name + ".png?cell=20&corner=500":           http.StatusBadRequest,
+		"/u/" + fakeanilist.EmptyUser + ".png?layout=rect": http.StatusUnprocessableEntity,
+	}
+
+	for target, want := range tests {
+		if rec := get(h, target, nil); rec.Code != want {
+			t.Errorf("Expected %d for %s, got %d", want, target, rec.Code)
+		}
+	}
+}
+
+func TestHandlerParse(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	g, err := h.parse(map[string][]string{
+		"layout": {"rect"}, "width": {"400"}, "height": {"100"},
+		"header": {"true"}, "badges": {"1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Width != 400 || g.Height != 100 || g.Header == nil || !g.Renderer.Overlay.Badges {
+		t.Errorf("Expected the query applied to the grid, got %+v", g)
+	}
+	if h.Grid.Header != nil || h.Grid.Layout != hexgrid.LayoutRings {
+		t.Errorf("Expected the grid of the handler to be left untouched")
+	}
+
+	// Width and height are only used by the rect layout
+	g, err = h.parse(map[string][]string{"width": {"400"}})
+	if err != nil || g.Width != 300 {
+		t.Errorf("Expected width to be ignored by rings, got %d, %v", g.Width, err)
+	}
+
+	// Stroke width is capped, gap and corner by the cell
+	limits := map[string]bool{
+		"stroke-width=20":    true,
+		"stroke-width=21":    false,
+		"cell=30&gap=30":     true,
+		"cell=30&gap=31":     false,
+		"cell=30&corner=30":  true,
+		"cell=30&corner=1e9": false,
+	}
+	for query, ok := range limits {
+		values, _ := url.ParseQuery(query)
+		if _, err := h.parse(values); (err == nil) != ok {
+			t.Errorf("Expected %s to be accepted: %t, got %v", query, ok, err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package source
+
+import (
+	"sync"
+
+	"github.com/Nadim147c/hexanilist/anilist"
+	"github.com/Nadim147c/hexanilist/hexgrid"
+)
+
+// Anilist builds nodes from an AniList user, their favourites and their
+// anime and manga lists.
+type Anilist struct {
+	User  anilist.User
+	Anime anilist.AnimeList
+	Manga anilist.MangaList
+}
+
+// Nodes returns the user, their favourite characters and their list entries.
+func (s Anilist) Nodes() ([]hexgrid.Node, error) {
+	return buildNodes(s.User, s.Anime, s.Manga), nil
+}
+
+func buildNodes(user anilist.User, anime anilist.AnimeList, manga anilist.MangaList) []hexgrid.Node {
+	userNode := hexgrid.Node{
+		Type:  hexgrid.UserNode,
+		ID:    user.ID,
+		Score: 1 << 60,
+		Image: string(user.Avatar.Medium),
+		Label: user.Name,
+		Link:  user.SiteURL,
+	}
+
+	nodes := []hexgrid.Node{userNode}
+	nodes = append(nodes, buildCharacterNodes(user)...)
+
+	nodeChan := make(chan hexgrid.Node)
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+	go processAnimeList(anime, user, nodeChan, &wg)
+	go processMangaList(manga, user, nodeChan, &wg)
+
+	go func() {
+		wg.Wait()
+		close(nodeChan)
+	}()
+
+	for node := range nodeChan {
+		nodes = append(nodes, node)
+	}
+
+	// Both lists send at once, sort to get the same order on every run
+	hexgrid.SortNodes(nodes)
+	return nodes
+}
+
+func buildCharacterNodes(user anilist.User) []hexgrid.Node {
+	var nodes []hexgrid.Node
+	for _, char := range user.Favourites.Characters.Nodes {
+		characterNode := hexgrid.Node{
+			Type:  hexgrid.CharacterNode,
+			ID:    char.ID,
+			Score: 500,
+			Image: string(char.Image.Medium),
+			Label: char.Name.UserPreferred,
+			Link:  char.SiteURL,
+		}
+		nodes = append(nodes, characterNode)
+	}
+	return nodes
+}
+
+func processAnimeList(anime anilist.AnimeList, user anilist.User, nodeChan chan<- hexgrid.Node, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for _, list := range anime.Lists {
+		for _, entry := range list.Entries {
+			favourite := user.Favourites.Anime.Has(entry.ID)
+			score := calculateScore(entry.Score, entry.Status, favourite)
+
+			animeNode := hexgrid.Node{
+				Type:  hexgrid.AnimeNode,
+				ID:    entry.ID,
+				Score: score,
+				Image: string(entry.Cover.Medium),
+				Label: entry.Title.UserPreferred,
+				Link:  entry.SiteURL,
+				Color: entry.Cover.ColorHex(),
+
+				Status:    string(entry.Status),
+				Favourite: favourite,
+			}
+			if entry.Score != nil {
+				animeNode.UserScore = *entry.Score
+			}
+
+			nodeChan <- animeNode
+		}
+	}
+}
+
+func processMangaList(manga anilist.MangaList, user anilist.User, nodeChan chan<- hexgrid.Node, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for _, list := range manga.Lists {
+		for _, entry := range list.Entries {
+			favourite := user.Favourites.Manga.Has(entry.ID)
+			score := calculateScore(entry.Score, entry.Status, favourite)
+
+			mangaNode := hexgrid.Node{
+				Type:  hexgrid.MangaNode, // Fixed: was AnimeNode, should be MangaNode
+				ID:    entry.ID,
+				Score: score,
+				Image: string(entry.Cover.Medium),
+				Label: entry.Title.UserPreferred,
+				Link:  entry.SiteURL,
+				Color: entry.Cover.ColorHex(),
+
+				Status:    string(entry.Status),
+				Favourite: favourite,
+			}
+			if entry.Score != nil {
+				mangaNode.UserScore = *entry.Score
+			}
+
+			nodeChan <- mangaNode
+		}
+	}
+}
+
+func calculateScore(userScore *float64, status anilist.Status, isFavorite bool) int {
+	var score int = 0
+
+	if userScore != nil {
+		score = int(*userScore * 10)
+	}
+
+	switch status {
+	case anilist.Completed:
+		score += 100
+	case anilist.Dropped:
+		score -= 100
+	}
+
+	if isFavorite {
+		score += 200
+	}
+
+	return score
+}
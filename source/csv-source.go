@@ -0,0 +1,159 @@
+package source
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+)
+
+// Record is a single row of a CSV source or a score sidecar.
+type Record struct {
+	Image string  `json:"image"` // Local path or url of the image.
+	Score float64 `json:"score"` // Score of the image, higher is closer to the center.
+	Label string  `json:"label"` // Title of the image.
+	Link  string  `json:"link"`  // Page of the image.
+	Color string  `json:"color"` // Colour as #rrggbb, used when the image is missing.
+}
+
+// Node converts the record to a node. Relative image paths are resolved
+// against dir. Scores keep one decimal, like the scores of AniList entries.
+func (r Record) Node(dir string) hexgrid.Node {
+	img := r.Image
+	if hexgrid.IsLocal(img) && !filepath.IsAbs(img) {
+		img = filepath.Join(dir, img)
+	}
+
+	label := r.Label
+	if label == "" {
+		base := filepath.Base(r.Image)
+		label = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+
+	return hexgrid.Node{
+		Type:  hexgrid.ImageNode,
+		Image: img,
+		Score: int(math.Round(r.Score * 10)),
+		Label: label,
+		Link:  r.Link,
+		Color: r.Color,
+	}
+}
+
+// ReadRecords reads records from a CSV or JSON file. CSV files need a header
+// naming the image, score, label, link and color columns; only image is
+// required. JSON files hold an array of records.
+func ReadRecords(path string) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var records []Record
+		if err := json.NewDecoder(file).Decode(&records); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		return records, nil
+	}
+
+	records, err := readCSV(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return records, nil
+}
+
+func readCSV(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	if _, ok := columns["image"]; !ok {
+		return nil, fmt.Errorf("missing image column")
+	}
+
+	field := func(row []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	var records []Record
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		record := Record{
+			Image: field(row, "image"),
+			Label: field(row, "label"),
+			Link:  field(row, "link"),
+			Color: field(row, "color"),
+		}
+
+		if record.Image == "" {
+			continue
+		}
+
+		if score := field(row, "score"); score != "" {
+			s, err := strconv.ParseFloat(score, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid score %q for %s", score, record.Image)
+			}
+			record.Score = s
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// CSV builds nodes from the rows of a CSV file with the columns image,
+// score, label and link. Images can be local paths, relative to the CSV
+// file, or urls.
+type CSV struct {
+	Path string
+}
+
+// Nodes returns a node for each row of the file.
+func (s CSV) Nodes() ([]hexgrid.Node, error) {
+	records, err := ReadRecords(s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(s.Path)
+
+	nodes := make([]hexgrid.Node, len(records))
+	for i, r := range records {
+		nodes[i] = r.Node(dir)
+	}
+
+	return nodes, nil
+}
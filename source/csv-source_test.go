@@ -0,0 +1,56 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+)
+
+func TestCSVSource(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "list.csv")
+
+	content := "image,score,label,link\n" +
+		"covers/a.png,90,Alpha,https://example.com/a\n" +
+		"https://example.com/b.jpg,7.5,,\n" +
+		",10,Empty,\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := CSV{Path: path}.Nodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nodes) != 2 {
+		t.Fatalf("Expected 2 nodes, got %d", len(nodes))
+	}
+
+	if nodes[0].Image != filepath.Join(dir, "covers", "a.png") {
+		t.Errorf("Expected image relative to the CSV file, got %s", nodes[0].Image)
+	}
+	if nodes[0].Score != 900 || nodes[0].Label != "Alpha" || nodes[0].Link != "https://example.com/a" {
+		t.Errorf("Unexpected first node %+v", nodes[0])
+	}
+
+	if nodes[1].Image != "https://example.com/b.jpg" || hexgrid.IsLocal(nodes[1].Image) {
+		t.Errorf("Expected url image, got %s", nodes[1].Image)
+	}
+	if nodes[1].Score != 75 || nodes[1].Label != "b" {
+		t.Errorf("Unexpected second node %+v", nodes[1])
+	}
+}
+
+func TestCSVSourceMissingImageColumn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.csv")
+	if err := os.WriteFile(path, []byte("score,label\n1,a\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := (CSV{Path: path}).Nodes(); err == nil {
+		t.Errorf("Expected error for missing image column")
+	}
+}
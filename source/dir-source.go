@@ -0,0 +1,92 @@
+package source
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+)
+
+// ImageExtensions are the file extensions Dir picks up. The render package
+// decodes all of them.
+var ImageExtensions = []string{".png", ".jpg", ".jpeg", ".gif", ".webp", ".bmp"}
+
+// SidecarNames are the score files Dir looks for inside the directory
+// when no sidecar is given.
+var SidecarNames = []string{"scores.json", "scores.csv"}
+
+// Dir builds a node from every image in a directory. Images are ordered
+// by file name. Scores, labels and links are read from the Sidecar records
+// whose image matches the file name.
+type Dir struct {
+	Dir     string
+	Sidecar string
+}
+
+// Nodes returns a node for each image in the directory.
+func (s Dir) Nodes() ([]hexgrid.Node, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	sidecar, err := s.records()
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []hexgrid.Node
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || !slices.Contains(ImageExtensions, ext) {
+			continue
+		}
+
+		record, ok := sidecar[entry.Name()]
+		if !ok {
+			record = Record{Image: entry.Name()}
+		}
+		record.Image = entry.Name()
+
+		nodes = append(nodes, record.Node(s.Dir))
+	}
+
+	return nodes, nil
+}
+
+// records reads the sidecar and indexes it by image file name.
+func (s Dir) records() (map[string]Record, error) {
+	path := s.Sidecar
+	if path == "" {
+		for _, name := range SidecarNames {
+			p := filepath.Join(s.Dir, name)
+			if _, err := os.Stat(p); err == nil {
+				path = p
+				break
+			}
+		}
+	}
+
+	index := make(map[string]Record)
+	if path == "" {
+		return index, nil
+	}
+
+	records, err := ReadRecords(path)
+	if errors.Is(err, fs.ErrNotExist) && s.Sidecar == "" {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range records {
+		index[filepath.Base(r.Image)] = r
+	}
+
+	return index, nil
+}
@@ -0,0 +1,70 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+)
+
+func TestDirSource(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.png", "a.JPG", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "nested.png"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := Dir{Dir: dir}.Nodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nodes) != 2 {
+		t.Fatalf("Expected 2 nodes, got %d", len(nodes))
+	}
+
+	if nodes[0].Label != "a" || nodes[1].Label != "b" {
+		t.Errorf("Expected labels a and b, got %s and %s", nodes[0].Label, nodes[1].Label)
+	}
+
+	if !hexgrid.IsLocal(nodes[0].Image) || nodes[0].Type != hexgrid.ImageNode {
+		t.Errorf("Expected local image node, got %+v", nodes[0])
+	}
+}
+
+func TestDirSourceSidecar(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.png", "b.png"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sidecar := `[{"image": "b.png", "score": 7.5, "label": "Bravo"}]`
+	if err := os.WriteFile(filepath.Join(dir, "scores.json"), []byte(sidecar), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := Dir{Dir: dir}.Nodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nodes) != 2 {
+		t.Fatalf("Expected 2 nodes, got %d", len(nodes))
+	}
+
+	if nodes[0].Score != 0 || nodes[0].Label != "a" {
+		t.Errorf("Unexpected node without sidecar record %+v", nodes[0])
+	}
+
+	// Fractional scores are read as in CSV files
+	if nodes[1].Score != 75 || nodes[1].Label != "Bravo" || nodes[1].Image != filepath.Join(dir, "b.png") {
+		t.Errorf("Unexpected node with sidecar record %+v", nodes[1])
+	}
+}
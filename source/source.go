@@ -0,0 +1,37 @@
+// Package source builds the nodes of a grid from an AniList profile, a
+// directory of images or a CSV list.
+package source
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+)
+
+// Source yields the nodes a grid is built from. Nodes with a higher score
+// are placed closer to the center.
+type Source interface {
+	Nodes() ([]hexgrid.Node, error)
+}
+
+// Hash returns a hash of the fields of nodes that decide their place on the
+// grid in the order: type, ID, image, scores, status and favourite, and the
+// colour for hexgrid.OrderColor. It is the same for nodes in any order, so a
+// grid only needs to be drawn again when it changes.
+func Hash(nodes []hexgrid.Node, order hexgrid.Order) string {
+	nodes = slices.Clone(nodes)
+	hexgrid.SortNodes(nodes)
+
+	h := sha256.New()
+	for _, n := range nodes {
+		fmt.Fprintf(h, "%v\t%d\t%q\t%d\t%g\t%q\t%t", n.Type, n.ID, n.Image, n.Score, n.UserScore, n.Status, n.Favourite)
+		if order == hexgrid.OrderColor {
+			fmt.Fprintf(h, "\t%q", n.Color)
+		}
+		fmt.Fprintln(h)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,54 @@
+package source
+
+import (
+	"testing"
+
+	"github.com/Nadim147c/hexanilist/hexgrid"
+)
+
+func TestHash(t *testing.T) {
+	nodes := []hexgrid.Node{
+		{Type: hexgrid.AnimeNode, ID: 1, Score: 180, UserScore: 8, Status: "COMPLETED"},
+		{Type: hexgrid.MangaNode, ID: 2, Score: 50, UserScore: 5, Status: "CURRENT"},
+	}
+	hash := Hash(nodes, hexgrid.OrderScore)
+
+	reversed := []hexgrid.Node{nodes[1], nodes[0]}
+	if Hash(reversed, hexgrid.OrderScore) != hash {
+		t.Errorf("Expected the hash not to depend on the order of the nodes")
+	}
+
+	relabelled := []hexgrid.Node{nodes[0], nodes[1]}
+	relabelled[0].Label = "Renamed"
+	if Hash(relabelled, hexgrid.OrderScore) != hash {
+		t.Errorf("Expected labels to be left out of the hash")
+	}
+
+	changes := map[string]func(n *hexgrid.Node){
+		"score":     func(n *hexgrid.Node) { n.UserScore = 9 },
+		"status":    func(n *hexgrid.Node) { n.Status = "DROPPED" },
+		"favourite": func(n *hexgrid.Node) { n.Favourite = true },
+		"id":        func(n *hexgrid.Node) { n.ID = 3 },
+	}
+	for name, change := range changes {
+		changed := []hexgrid.Node{nodes[0], nodes[1]}
+		change(&changed[0])
+		if Hash(changed, hexgrid.OrderScore) == hash {
+			t.Errorf("Expected a changed %s to change the hash", name)
+		}
+	}
+
+	if Hash(nodes[:1], hexgrid.OrderScore) == hash {
+		t.Errorf("Expected a removed node to change the hash")
+	}
+
+	// The colour only moves nodes with the color order
+	recoloured := []hexgrid.Node{nodes[0], nodes[1]}
+	recoloured[0].Color = "#ff0000"
+	if Hash(recoloured, hexgrid.OrderScore) != hash {
+		t.Errorf("Expected the colour to be left out of the hash of the score order")
+	}
+	if Hash(recoloured, hexgrid.OrderColor) == Hash(nodes, hexgrid.OrderColor) {
+		t.Errorf("Expected a changed colour to change the hash of the color order")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/Nadim147c/hexanilist/anilist"
+	"github.com/Nadim147c/hexanilist/render"
+)
+
+// watch draws the grid, then polls the nodes every interval and draws it
+// again when they change, until ctx is cancelled. Failed updates are logged
+// and retried at the next poll.
+func watch(ctx context.Context, cache *render.ImageCache, client *anilist.Client, grid render.Grid, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	slog.Info("Watching for changes", "interval", interval)
+
+	var last string
+	for {
+		hash, err := update(ctx, cache, client, grid, last)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			slog.Error("Failed to update grid", "error", err)
+		}
+		last = hash
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// runHook runs the command through the shell after output was written. The
+// path of the output is in $HEXANILIST_OUTPUT.
+func runHook(ctx context.Context, command, output string) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	cmd.Env = append(os.Environ(), "HEXANILIST_OUTPUT="+output)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	slog.Info("Running post-render hook", "command", command)
+	return cmd.Run()
+}